@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"hdf5_test2/converter"
+)
+
+// Configuration de la conversion. Chaque option peut être fixée par un profil nommé,
+// par un fichier de configuration JSON (clé = tag json) et par une option de la ligne
+// de commande (nom = tag json, "_" remplacés par "-"), dans cet ordre de priorité croissante.
+type Config struct {
+	Profile            string  `json:"profile" help:"profil de réglages prédéfinis : archive ou analysis"`
+	Format             string  `json:"format" help:"format des séries : matrix (matrice 2-D de float64), compound (enregistrements t, value), cf (NetCDF-4, conventions CF), pandas (format table de PyTables) ou mat (MAT-file v7.3 de MATLAB)"`
+	Metadata           string  `json:"metadata" help:"stockage des métadonnées l/a/x : attributes (attributs l_*, a_*, x_*), record (attribut composé \"meta\") ou both"`
+	StrictFields       bool    `json:"strict_fields" help:"rejeter les entrées contenant des champs JSON inconnus au lieu de les conserver en attributs x_*"`
+	Strict             bool    `json:"strict" help:"échouer sur la première valeur de V non convertie (chaîne illisible, nombre hors plage, type non supporté, null) au lieu de la remplacer par 0.0"`
+	Compression        int     `json:"compression" help:"niveau de compression GZIP des datasets chunkés, de 0 (aucune) à 9"`
+	Shuffle            bool    `json:"shuffle" help:"appliquer le filtre shuffle avant la compression des datasets chunkés (meilleur taux de compression des float64)"`
+	DirectChunks       bool    `json:"direct_chunks" help:"compresser les chunks des matrices en parallèle (workers) et les écrire directement (H5Dwrite_chunk, HDF5 1.10.3 ou plus)"`
+	BlockRows          int     `json:"block_rows" help:"écrire les séries par blocs de ce nombre de lignes (arrondi à un nombre entier de chunks) ; 0 : en une seule fois"`
+	Chunking           string  `json:"chunking" help:"forme des chunks des matrices : column (une colonne par chunk), row (une ligne par chunk) ou matrix (un seul chunk)"`
+	CompactMaxBytes    int     `json:"compact_max_bytes" help:"taille maximale (octets) d'un dataset en stockage compact"`
+	ContiguousMaxBytes int     `json:"contiguous_max_bytes" help:"taille maximale (octets) d'un dataset en stockage contigu ; au-delà, chunké et compressé"`
+	ReverseRows        bool    `json:"reverse_rows" help:"inverser l'ordre des lignes de V (les données JSON sont les plus récentes en premier)"`
+	TimestampDivisor   float64 `json:"timestamp_divisor" help:"diviseur appliqué aux horodatages (colonne 0) : 1000 pour passer des ms aux s"`
+	Append             bool    `json:"append" help:"ajouter aux séries d'un fichier existant les lignes postérieures à leur dernier horodatage (formats matrix et compound)"`
+	KeepGoing          bool    `json:"keep_going" help:"ignorer les séries en échec (supprimées du fichier) au lieu d'interrompre la conversion"`
+	Workers            int     `json:"workers" help:"nombre de goroutines de décodage et de prétraitement des entrées (0 : une par processeur)"`
+}
+
+// Configuration par défaut, qui reproduit le comportement historique du convertisseur
+func defaultConfig() Config {
+	opts := converter.DefaultOptions()
+	return Config{
+		Format:             opts.Format,
+		Metadata:           "attributes",
+		StrictFields:       opts.StrictFields,
+		Strict:             opts.StrictValues,
+		Compression:        opts.Compression,
+		Shuffle:            opts.Shuffle,
+		DirectChunks:       opts.DirectChunks,
+		BlockRows:          opts.BlockRows,
+		Chunking:           opts.Chunking,
+		CompactMaxBytes:    opts.CompactMaxBytes,
+		ContiguousMaxBytes: opts.ContiguousMaxBytes,
+		ReverseRows:        opts.ReverseRows,
+		TimestampDivisor:   opts.TimestampDivisor,
+		Append:             opts.Append,
+		KeepGoing:          opts.KeepGoing,
+		Workers:            opts.Workers,
+	}
+}
+
+// Profils nommés, partagés entre équipes : seules les options renseignées (au format
+// du fichier de configuration) remplacent la configuration par défaut
+var configProfiles = map[string]string{
+	// Archivage : fichiers autoporteurs (métadonnées en attributs et en enregistrement),
+	// compression maximale, aucune donnée partielle acceptée
+	"archive": `{"metadata": "both", "compression": 9, "chunking": "column", "strict_fields": true}`,
+	// Analyse : lecture rapide par lignes, compression légère, enregistrements (t, value)
+	"analysis": `{"format": "compound", "compression": 1, "chunking": "row", "contiguous_max_bytes": 4194304}`,
+}
+
+// Fonction auxiliaire pour appliquer un document JSON de configuration (profil ou fichier)
+func applyConfigJSON(cfg *Config, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(cfg)
+}
+
+// Fonction auxiliaire pour appliquer un profil nommé
+func applyProfile(cfg *Config, name string) error {
+	profile, ok := configProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(configProfiles))
+		for n := range configProfiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profil inconnu '%s' (profils disponibles : %s)", name, strings.Join(names, ", "))
+	}
+	if err := applyConfigJSON(cfg, []byte(profile)); err != nil {
+		return err
+	}
+	cfg.Profile = name
+	return nil
+}
+
+// Fonction pour vérifier la cohérence d'une configuration
+func (cfg *Config) validate() error {
+	switch cfg.Metadata {
+	case "attributes", "record", "both":
+	default:
+		return fmt.Errorf("mode de stockage des métadonnées inconnu: '%s'", cfg.Metadata)
+	}
+	return cfg.options().Validate()
+}
+
+// Options de conversion correspondant à la configuration
+func (cfg *Config) options() converter.Options {
+	return converter.Options{
+		Format:               cfg.Format,
+		MetadataInAttributes: cfg.Metadata == "attributes" || cfg.Metadata == "both",
+		MetadataInRecord:     cfg.Metadata == "record" || cfg.Metadata == "both",
+		StrictFields:         cfg.StrictFields,
+		StrictValues:         cfg.Strict,
+		Compression:          cfg.Compression,
+		Shuffle:              cfg.Shuffle,
+		DirectChunks:         cfg.DirectChunks,
+		BlockRows:            cfg.BlockRows,
+		Chunking:             cfg.Chunking,
+		CompactMaxBytes:      cfg.CompactMaxBytes,
+		ContiguousMaxBytes:   cfg.ContiguousMaxBytes,
+		ReverseRows:          cfg.ReverseRows,
+		TimestampDivisor:     cfg.TimestampDivisor,
+		Append:               cfg.Append,
+		KeepGoing:            cfg.KeepGoing,
+		Workers:              cfg.Workers,
+	}
+}
+
+// Options de configuration d'une commande : -config, et une option par champ de Config
+type configFlags struct {
+	path  *string
+	flags *flag.FlagSet
+	value Config // valeurs données sur la ligne de commande
+}
+
+// Fonction pour déclarer les options de configuration sur le jeu d'options d'une commande
+func addConfigFlags(flags *flag.FlagSet) *configFlags {
+	cf := &configFlags{flags: flags, value: defaultConfig()}
+	cf.path = flags.String("config", "", "fichier de configuration JSON (les options de la ligne de commande sont prioritaires)")
+
+	rv := reflect.ValueOf(&cf.value).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		name := configFlagName(field)
+		help := field.Tag.Get("help")
+		switch ptr := rv.Field(i).Addr().Interface().(type) {
+		case *string:
+			flags.StringVar(ptr, name, *ptr, help)
+		case *bool:
+			flags.BoolVar(ptr, name, *ptr, help)
+		case *int:
+			flags.IntVar(ptr, name, *ptr, help)
+		case *float64:
+			flags.Float64Var(ptr, name, *ptr, help)
+		}
+	}
+	return cf
+}
+
+// Nom de l'option de la ligne de commande d'un champ de Config
+func configFlagName(field reflect.StructField) string {
+	return strings.ReplaceAll(field.Tag.Get("json"), "_", "-")
+}
+
+// Fonction pour construire la configuration après l'analyse des options : valeurs par
+// défaut, puis profil, puis fichier de configuration, puis options données explicitement
+func (cf *configFlags) config() (Config, error) {
+	cfg := defaultConfig()
+
+	// Options données explicitement
+	set := make(map[string]bool)
+	cf.flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// Fichier de configuration, lu une première fois pour connaître son profil
+	var fileData []byte
+	var fileCfg Config
+	if *cf.path != "" {
+		data, err := os.ReadFile(*cf.path)
+		if err != nil {
+			return cfg, fmt.Errorf("lecture du fichier de configuration: %v", err)
+		}
+		if err := applyConfigJSON(&fileCfg, data); err != nil {
+			return cfg, fmt.Errorf("fichier de configuration %s: %v", *cf.path, err)
+		}
+		fileData = data
+	}
+
+	profile := fileCfg.Profile
+	if set["profile"] {
+		profile = cf.value.Profile
+	}
+	if profile != "" {
+		if err := applyProfile(&cfg, profile); err != nil {
+			return cfg, err
+		}
+	}
+	if fileData != nil {
+		if err := applyConfigJSON(&cfg, fileData); err != nil {
+			return cfg, fmt.Errorf("fichier de configuration %s: %v", *cf.path, err)
+		}
+	}
+
+	rv, flagValues := reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(cf.value)
+	for i := 0; i < rv.NumField(); i++ {
+		if set[configFlagName(rv.Type().Field(i))] {
+			rv.Field(i).Set(flagValues.Field(i))
+		}
+	}
+	cfg.Profile = profile
+
+	return cfg, cfg.validate()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// Priorité des réglages : valeurs par défaut < profil < fichier de configuration < options
+// données explicitement sur la ligne de commande
+func TestConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string   // contenu du fichier de configuration ("" : pas de fichier)
+		args []string // options de la ligne de commande
+		want func(*Config)
+	}{
+		{name: "valeurs par défaut", want: func(cfg *Config) {}},
+		{
+			name: "profil",
+			args: []string{"-profile", "analysis"},
+			want: func(cfg *Config) {
+				cfg.Profile = "analysis"
+				cfg.Format = "compound"
+				cfg.Compression = 1
+				cfg.Chunking = "row"
+				cfg.ContiguousMaxBytes = 4194304
+			},
+		},
+		{
+			name: "fichier après le profil",
+			file: `{"profile": "analysis", "compression": 5}`,
+			want: func(cfg *Config) {
+				cfg.Profile = "analysis"
+				cfg.Format = "compound"
+				cfg.Compression = 5
+				cfg.Chunking = "row"
+				cfg.ContiguousMaxBytes = 4194304
+			},
+		},
+		{
+			name: "option après le fichier",
+			file: `{"compression": 5, "keep_going": true}`,
+			args: []string{"-compression", "3"},
+			want: func(cfg *Config) {
+				cfg.Compression = 3
+				cfg.KeepGoing = true
+			},
+		},
+		{
+			name: "option égale à la valeur par défaut",
+			file: `{"compression": 5}`,
+			args: []string{"-compression", "9"},
+			want: func(cfg *Config) {},
+		},
+		{
+			name: "profil de la ligne de commande",
+			file: `{"profile": "archive", "workers": 2}`,
+			args: []string{"-profile", "analysis", "-keep-going", "-block-rows", "4096"},
+			want: func(cfg *Config) {
+				cfg.Profile = "analysis"
+				cfg.Format = "compound"
+				cfg.Compression = 1
+				cfg.Chunking = "row"
+				cfg.ContiguousMaxBytes = 4194304
+				cfg.Workers = 2
+				cfg.KeepGoing = true
+				cfg.BlockRows = 4096
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(t, tt.file, tt.args)
+			if err != nil {
+				t.Fatalf("configuration: %v", err)
+			}
+			want := defaultConfig()
+			tt.want(&want)
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("configuration %+v, attendu %+v", cfg, want)
+			}
+		})
+	}
+}
+
+// Réglages rejetés : profil inconnu, clé inconnue du fichier, valeur invalide
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		args []string
+	}{
+		{name: "profil inconnu", args: []string{"-profile", "inconnu"}},
+		{name: "profil inconnu du fichier", file: `{"profile": "inconnu"}`},
+		{name: "clé inconnue", file: `{"compresion": 5}`},
+		{name: "compression invalide", file: `{"compression": 12}`},
+		{name: "métadonnées invalides", args: []string{"-metadata", "none"}},
+		{name: "ajout au format cf", args: []string{"-format", "cf", "-append"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cfg, err := parseConfig(t, tt.file, tt.args); err == nil {
+				t.Errorf("configuration %+v acceptée", cfg)
+			}
+		})
+	}
+}
+
+// Fonction auxiliaire pour construire la configuration d'une commande à partir d'un
+// fichier de configuration (ignoré si vide) et d'options de la ligne de commande
+func parseConfig(t *testing.T, file string, args []string) (Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	cf := addConfigFlags(flags)
+	if file != "" {
+		args = append([]string{"-config", writeTempFile(t, t.TempDir(), "config.json", file)}, args...)
+	}
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("analyse des options %q: %v", args, err)
+	}
+	return cf.config()
+}
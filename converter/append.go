@@ -0,0 +1,317 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Mode ajout : les séries déjà présentes dans le fichier sont prolongées des lignes
+// postérieures à leur dernier horodatage. Les datasets doivent avoir été créés en mode
+// ajout (chunkés, nombre de lignes illimité) ; les nouvelles séries sont écrites normalement.
+
+// Résultat de l'ajout d'une entrée à un dataset existant
+type appendResult struct {
+	Added          int     // lignes ajoutées
+	Skipped        int     // lignes ignorées, antérieures ou égales au dernier horodatage
+	Rows           int     // nombre total de lignes du dataset
+	Cols           int     // nombre de colonnes
+	FirstTimestamp float64 // premier horodatage du dataset (après conversion)
+	LastTimestamp  float64 // dernier horodatage du dataset
+}
+
+// Fonction pour prolonger le dataset name avec les lignes de l'entrée postérieures à son
+// dernier horodatage, puis remplacer ses attributs par ceux de l'entrée. En cas d'échec, le
+// dataset garde ses lignes et ses attributs d'origine.
+func appendEntry(loc location, name, baseName string, entry DataEntryFloat, opts Options) (appendResult, error) {
+	var result appendResult
+
+	dset, err := loc.OpenDataset(name)
+	if err != nil {
+		return result, err
+	}
+	defer dset.Close()
+
+	space := dset.Space()
+	if space == nil {
+		return result, fmt.Errorf("espace de données inaccessible")
+	}
+	dims, maxDims, err := space.SimpleExtentDims()
+	space.Close()
+	if err != nil {
+		return result, err
+	}
+	if len(maxDims) == 0 || maxDims[0] != hdf5.S_UNLIMITED {
+		return result, fmt.Errorf("dataset non extensible (créé sans le mode ajout)")
+	}
+
+	// Le dataset existant doit avoir le format et le nombre de colonnes de l'entrée
+	cols := len(entry.V[0])
+	var record *recordLayout
+	switch {
+	case opts.Format == FormatMatrix && len(dims) == 2:
+		if int(dims[1]) != cols {
+			return result, fmt.Errorf("%d colonnes dans le fichier, %d dans l'entrée", dims[1], cols)
+		}
+	case opts.Format == FormatCompound && len(dims) == 1:
+		dtype, err := dset.Datatype()
+		if err != nil {
+			return result, err
+		}
+		record, err = recordLayoutOf(dtype)
+		dtype.Close()
+		if err != nil {
+			return result, err
+		}
+		if len(record.Fields) != cols || record.fieldIndex("t") != 0 {
+			return result, fmt.Errorf("enregistrements de %d champs dans le fichier, %d colonnes dans l'entrée", len(record.Fields), cols)
+		}
+	default:
+		return result, fmt.Errorf("dataset de rang %d incompatible avec le format '%s'", len(dims), opts.Format)
+	}
+
+	// Bornes des horodatages déjà stockés
+	stored := dims[0]
+	first, last := math.NaN(), math.Inf(-1)
+	if stored > 0 {
+		if first, err = readTimestampAt(dset, record, len(dims), 0, opts); err != nil {
+			return result, err
+		}
+		if last, err = readTimestampAt(dset, record, len(dims), stored-1, opts); err != nil {
+			return result, err
+		}
+	}
+
+	// Ne garder que les lignes postérieures au dernier horodatage stocké ; en format
+	// compound, la comparaison se fait dans l'unité entière d'origine
+	var rows [][]float64
+	for _, row := range entry.V {
+		t := row[0]
+		if record != nil {
+			t = math.Round(t*opts.TimestampDivisor) / opts.TimestampDivisor
+		}
+		if t > last {
+			rows = append(rows, row)
+		} else {
+			result.Skipped++
+		}
+	}
+	result.Added = len(rows)
+
+	// Les métadonnées de l'entrée remplacent celles du fichier ; les compteurs de valeurs
+	// non converties s'additionnent. Les nouveaux attributs sont d'abord écrits sous un nom
+	// temporaire : le dataset n'est modifié qu'une fois toutes les écritures réussies.
+	names, err := attributeNames(dset)
+	if err != nil {
+		return result, err
+	}
+	var oldNames []string
+	entry.WarningCounts = append([]WarningCount(nil), entry.WarningCounts...)
+	for _, attrName := range names {
+		// Attributs temporaires laissés par un ajout interrompu
+		if strings.HasPrefix(attrName, stagedAttributePrefix) || strings.HasPrefix(attrName, backupAttributePrefix) {
+			if err := dset.DeleteAttribute(attrName); err != nil {
+				return result, fmt.Errorf("suppression de l'attribut temporaire '%s': %v", attrName, err)
+			}
+			continue
+		}
+		if strings.HasPrefix(attrName, warningAttributePrefix) {
+			value, err := readAttribute(dset, attrName)
+			if err != nil {
+				return result, fmt.Errorf("lecture de l'attribut '%s': %v", attrName, err)
+			}
+			entry.WarningCounts = mergeWarningAttribute(entry.WarningCounts, attrName, value)
+		}
+		oldNames = append(oldNames, attrName)
+	}
+	addStateAttributes(&entry)
+	metadata, duplicates := entryMetadata(entry)
+	for _, attrName := range duplicates {
+		opts.logger().Warn("Métadonnée en double ignorée", "event", "duplicate_metadata", "channel", baseName, "path", "/"+name, "attribute", attrName)
+	}
+	staged := &stagedAttributes{dset: dset}
+	if err := writeEntryAttributes(staged, name, baseName, entry, metadata, opts); err != nil {
+		staged.discard()
+		return result, err
+	}
+
+	// Lignes postérieures au dernier horodatage ; en cas d'échec, le dataset garde sa
+	// taille d'origine (writeRowsAt)
+	if len(rows) > 0 {
+		if err := writeRowsAt(dset, record, dims, rows, opts); err != nil {
+			staged.discard()
+			return result, err
+		}
+		if stored == 0 {
+			first = rows[0][0]
+		}
+		last = math.Max(last, rows[len(rows)-1][0])
+	}
+
+	// Remplacement des attributs ; en cas d'échec, les anciens attributs sont restaurés et
+	// les lignes ajoutées retirées
+	if err := staged.commit(oldNames, opts); err != nil {
+		if len(rows) > 0 {
+			if shrinkErr := dset.SetExtent(dims); shrinkErr != nil {
+				return result, fmt.Errorf("%v (retour à la taille d'origine impossible: %v)", err, shrinkErr)
+			}
+		}
+		return result, err
+	}
+
+	result.Rows = int(stored) + len(rows)
+	result.Cols = cols
+	result.FirstTimestamp = first
+	result.LastTimestamp = last
+	if math.IsInf(last, -1) {
+		result.LastTimestamp = math.NaN()
+	}
+	return result, nil
+}
+
+// Préfixes des noms temporaires d'attributs pendant le remplacement des métadonnées
+const (
+	stagedAttributePrefix = "~new~" // nouvel attribut, en attente
+	backupAttributePrefix = "~old~" // ancien attribut, supprimé une fois le remplacement fait
+)
+
+// Attributs écrits sous un nom temporaire (stagedAttributePrefix), en attendant de
+// remplacer ceux du dataset
+type stagedAttributes struct {
+	dset  *hdf5.Dataset
+	names []string // noms définitifs des attributs créés
+}
+
+func (s *stagedAttributes) CreateAttribute(name string, dtype *hdf5.Datatype, dspace *hdf5.Dataspace) (*hdf5.Attribute, error) {
+	attr, err := s.dset.CreateAttribute(stagedAttributePrefix+name, dtype, dspace)
+	if err == nil {
+		s.names = append(s.names, name)
+	}
+	return attr, err
+}
+
+// Fonction pour supprimer les attributs temporaires, après un échec
+func (s *stagedAttributes) discard() {
+	for _, name := range s.names {
+		s.dset.DeleteAttribute(stagedAttributePrefix + name)
+	}
+	s.names = nil
+}
+
+// Fonction pour remplacer les attributs oldNames du dataset par les attributs temporaires.
+// Les anciens attributs sont d'abord renommés, puis les nouveaux reçoivent leur nom
+// définitif ; si un renommage échoue, tout est remis en place et les attributs temporaires
+// sont supprimés. Un ancien attribut qui ne peut pas être supprimé à la fin est signalé
+// sans faire échouer l'ajout.
+func (s *stagedAttributes) commit(oldNames []string, opts Options) error {
+	var backedUp, renamed []string
+	undo := func() {
+		for _, name := range renamed {
+			s.dset.RenameAttribute(name, stagedAttributePrefix+name)
+		}
+		for _, name := range backedUp {
+			s.dset.RenameAttribute(backupAttributePrefix+name, name)
+		}
+		s.discard()
+	}
+
+	for _, name := range oldNames {
+		if err := s.dset.RenameAttribute(name, backupAttributePrefix+name); err != nil {
+			undo()
+			return fmt.Errorf("renommage de l'attribut '%s': %v", name, err)
+		}
+		backedUp = append(backedUp, name)
+	}
+	for _, name := range s.names {
+		if err := s.dset.RenameAttribute(stagedAttributePrefix+name, name); err != nil {
+			undo()
+			return fmt.Errorf("renommage de l'attribut '%s': %v", name, err)
+		}
+		renamed = append(renamed, name)
+	}
+
+	for _, name := range backedUp {
+		if err := s.dset.DeleteAttribute(backupAttributePrefix + name); err != nil {
+			opts.logger().Warn("Ancien attribut non supprimé", "event", "backup_attribute_not_removed", "attribute", backupAttributePrefix+name, "error", err)
+		}
+	}
+	s.names = nil
+	return nil
+}
+
+// Fonction auxiliaire pour lire l'horodatage (après conversion) de la ligne row d'un dataset
+// matrice (record nil) ou d'enregistrements composés
+func readTimestampAt(dset *hdf5.Dataset, record *recordLayout, rank int, row uint, opts Options) (float64, error) {
+	filespace := dset.Space()
+	if filespace == nil {
+		return 0, fmt.Errorf("espace de données inaccessible")
+	}
+	defer filespace.Close()
+
+	offset, count := []uint{row}, []uint{1}
+	if rank == 2 {
+		offset, count = []uint{row, 0}, []uint{1, 1}
+	}
+	if err := filespace.SelectHyperslab(offset, nil, count, nil); err != nil {
+		return 0, err
+	}
+	memspace, err := hdf5.CreateSimpleDataspace(count, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer memspace.Close()
+
+	if record == nil {
+		value := make([]float64, 1)
+		if err := dset.ReadSubset(&value, memspace, filespace); err != nil {
+			return 0, err
+		}
+		return value[0], nil
+	}
+
+	buf := make([]byte, record.Size)
+	if err := dset.ReadSubset(&buf, memspace, filespace); err != nil {
+		return 0, err
+	}
+	return float64(record.get(buf, 0).(int64)) / opts.TimestampDivisor, nil
+}
+
+// Fonction auxiliaire pour étendre un dataset de dims et écrire rows à la suite de ses
+// lignes, par blocs de opts.BlockRows lignes (sélection hyperslab des nouvelles lignes)
+func writeRowsAt(dset *hdf5.Dataset, record *recordLayout, dims []uint, rows [][]float64, opts Options) error {
+	newDims := append([]uint(nil), dims...)
+	newDims[0] += uint(len(rows))
+	if err := dset.SetExtent(newDims); err != nil {
+		return fmt.Errorf("extension du dataset: %v", err)
+	}
+
+	var err error
+	block := blockRows(len(rows), opts)
+	if record != nil {
+		err = writeBlocks(len(rows), block, func(start, n int) error {
+			buf := encodeCompoundRecords(record, rows[start:start+n], opts)
+			return writeRowsSubset(dset, dims[0]+uint(start), uint(n), 0, &buf)
+		})
+	} else {
+		cols := int(dims[1])
+		flatData := make([]float64, block*cols)
+		err = writeBlocks(len(rows), block, func(start, n int) error {
+			data := flatData[:n*cols]
+			clear(data)
+			for i, row := range rows[start : start+n] {
+				copy(data[i*cols:(i+1)*cols], row[:min(cols, len(row))])
+			}
+			return writeRowsSubset(dset, dims[0]+uint(start), uint(n), dims[1], &data)
+		})
+	}
+
+	// En cas d'échec, le dataset retrouve sa taille d'origine
+	if err != nil {
+		if shrinkErr := dset.SetExtent(dims); shrinkErr != nil {
+			return fmt.Errorf("%v (retour à la taille d'origine impossible: %v)", err, shrinkErr)
+		}
+	}
+	return err
+}
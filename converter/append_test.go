@@ -0,0 +1,326 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Fonction auxiliaire pour convertir un JSON source dans le fichier path, créé s'il
+// n'existe pas et ouvert en écriture sinon (mode ajout)
+func convertFile(t *testing.T, path, input string, opts Options) (Report, error) {
+	t.Helper()
+	var f *hdf5.File
+	var err error
+	if _, statErr := os.Stat(path); opts.Append && statErr == nil {
+		f, err = hdf5.OpenFile(path, hdf5.F_ACC_RDWR)
+	} else {
+		f, err = hdf5.CreateFile(path, hdf5.F_ACC_TRUNC)
+	}
+	if err != nil {
+		t.Fatalf("ouverture de '%s': %v", path, err)
+	}
+	defer f.Close()
+	return Convert(context.Background(), strings.NewReader(input), f, opts)
+}
+
+// Fonction auxiliaire pour relire les lignes d'une série et les noms de ses attributs
+func readSeries(t *testing.T, path, name string) ([][]float64, []string) {
+	t.Helper()
+	f, err := hdf5.OpenFile(path, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		t.Fatalf("ouverture de '%s': %v", path, err)
+	}
+	defer f.Close()
+	dset, err := f.OpenDataset(name)
+	if err != nil {
+		t.Fatalf("ouverture de '%s': %v", name, err)
+	}
+	defer dset.Close()
+
+	rows, ok, err := readEntryRows(dset, DefaultOptions().TimestampDivisor)
+	if err != nil || !ok {
+		t.Fatalf("lecture des lignes de '%s': (%v, %v)", name, ok, err)
+	}
+	names, err := attributeNames(dset)
+	if err != nil {
+		t.Fatalf("lecture des attributs de '%s': %v", name, err)
+	}
+	sort.Strings(names)
+	return rows, names
+}
+
+// Prolongement d'une série : seules les lignes postérieures au dernier horodatage sont
+// ajoutées, les attributs de l'entrée remplacent ceux du fichier (compteurs
+// d'avertissements additionnés) et la table d'index est mise à jour
+func TestAppendEntry(t *testing.T) {
+	const (
+		first  = `[[{"c": "a", "l": {"site": "nord", "old": 1}, "v": [[2000, null], [1000, 1]]}]]`
+		second = `[[{"c": "a", "l": {"site": "sud"}, "v": [[3000, null], [2000, 9], [1500, 9]]}, {"c": "b", "v": [[1000, 5]]}]]`
+	)
+	for _, format := range []string{FormatMatrix, FormatCompound} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "append.h5")
+			opts := DefaultOptions()
+			opts.Format = format
+			opts.Append = true
+			if _, err := convertFile(t, path, first, opts); err != nil {
+				t.Fatalf("première conversion: %v", err)
+			}
+
+			report, err := convertFile(t, path, second, opts)
+			if err != nil {
+				t.Fatalf("ajout: %v", err)
+			}
+			if report.Extended != 1 || report.AddedRows != 1 || report.SkippedRows != 2 {
+				t.Errorf("rapport: %d séries prolongées, %d lignes ajoutées, %d ignorées, attendu 1, 1, 2",
+					report.Extended, report.AddedRows, report.SkippedRows)
+			}
+
+			rows, names := readSeries(t, path, "a")
+			want := [][]float64{{1, 1}, {2, 0}, {3, 0}}
+			if !reflect.DeepEqual(rows, want) {
+				t.Errorf("lignes %v, attendu %v", rows, want)
+			}
+			for _, name := range names {
+				if strings.HasPrefix(name, stagedAttributePrefix) || strings.HasPrefix(name, backupAttributePrefix) {
+					t.Errorf("attribut temporaire '%s' laissé sur le dataset", name)
+				}
+				if name == "l_old" {
+					t.Errorf("ancien attribut 'l_old' conservé")
+				}
+			}
+
+			f, err := hdf5.OpenFile(path, hdf5.F_ACC_RDONLY)
+			if err != nil {
+				t.Fatalf("ouverture: %v", err)
+			}
+			defer f.Close()
+			dset, err := f.OpenDataset("a")
+			if err != nil {
+				t.Fatalf("ouverture de 'a': %v", err)
+			}
+			defer dset.Close()
+			if site, err := readAttribute(dset, "l_site"); err != nil || site != "sud" {
+				t.Errorf("l_site: (%v, %v), attendu sud", site, err)
+			}
+			if count, err := readAttribute(dset, warningAttributePrefix+WarningNull); err != nil || count != int64(2) {
+				t.Errorf("%s: (%v, %v), attendu 2", warningAttributePrefix+WarningNull, count, err)
+			}
+
+			index, err := readIndexRows(f)
+			if err != nil {
+				t.Fatalf("lecture de la table d'index: %v", err)
+			}
+			if len(index) != 2 || index[0].Path != "/a" || index[1].Path != "/b" {
+				t.Fatalf("table d'index %+v, attendu /a puis /b", index)
+			}
+			if index[0].Rows != 3 || index[0].FirstTimestamp != 1 || index[0].LastTimestamp != 3 || index[0].Warnings != 2 {
+				t.Errorf("ligne d'index de /a %+v, attendu 3 lignes de 1 à 3, 2 avertissements", index[0])
+			}
+		})
+	}
+}
+
+// Un ajout impossible laisse la série intacte ; en mode keep-going, il est décrit dans le
+// rapport et la conversion continue
+func TestAppendEntryMismatch(t *testing.T) {
+	const (
+		first  = `[[{"c": "a", "l": {"site": "nord"}, "v": [[1000, 1]]}]]`
+		second = `[[{"c": "a", "l": {"site": "sud"}, "v": [[2000, 2, 3]]}, {"c": "b", "v": [[1000, 5]]}]]`
+	)
+	path := filepath.Join(t.TempDir(), "append.h5")
+	opts := DefaultOptions()
+	opts.Append = true
+	if _, err := convertFile(t, path, first, opts); err != nil {
+		t.Fatalf("première conversion: %v", err)
+	}
+
+	_, err := convertFile(t, path, second, opts)
+	var entryErr *EntryError
+	if !errors.As(err, &entryErr) || entryErr.Stage != StageAppend {
+		t.Fatalf("ajout: erreur %v, attendu *EntryError à l'étape %q", err, StageAppend)
+	}
+
+	opts.KeepGoing = true
+	report, err := convertFile(t, path, second, opts)
+	if err != nil {
+		t.Fatalf("ajout en mode keep-going: %v", err)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Path != "/a" || report.Entries != 1 {
+		t.Errorf("rapport: %d séries écrites, échecs %+v, attendu 1 série et un échec sur /a",
+			report.Entries, report.Failures)
+	}
+
+	rows, _ := readSeries(t, path, "a")
+	if want := [][]float64{{1, 1}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("lignes %v, attendu %v", rows, want)
+	}
+	f, err := hdf5.OpenFile(path, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		t.Fatalf("ouverture: %v", err)
+	}
+	defer f.Close()
+	dset, err := f.OpenDataset("a")
+	if err != nil {
+		t.Fatalf("ouverture de 'a': %v", err)
+	}
+	defer dset.Close()
+	if site, err := readAttribute(dset, "l_site"); err != nil || site != "nord" {
+		t.Errorf("l_site: (%v, %v), attendu nord", site, err)
+	}
+}
+
+// Remplacement des attributs : en cas d'échec d'un renommage, les attributs d'origine
+// sont restaurés et les attributs temporaires supprimés
+func TestStagedAttributesCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string // attributs présents sur le dataset
+		oldNames []string // attributs à remplacer
+		staged   []string // nouveaux attributs
+		want     []string // attributs après le remplacement
+		fail     bool
+	}{
+		{
+			name:     "remplacement",
+			existing: []string{"l_site", "l_old"},
+			oldNames: []string{"l_site", "l_old"},
+			staged:   []string{"l_site", "l_new"},
+			want:     []string{"l_new", "l_site"},
+		},
+		{
+			name:     "sauvegarde impossible",
+			existing: []string{"l_site", backupAttributePrefix + "l_site"},
+			oldNames: []string{"l_site"},
+			staged:   []string{"l_site"},
+			want:     []string{"l_site", backupAttributePrefix + "l_site"},
+			fail:     true,
+		},
+		{
+			name:     "renommage impossible",
+			existing: []string{"l_site", "l_new"},
+			oldNames: []string{"l_site"},
+			staged:   []string{"l_site", "l_new"},
+			want:     []string{"l_new", "l_site"},
+			fail:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "staged.h5"), hdf5.F_ACC_TRUNC)
+			if err != nil {
+				t.Fatalf("création du fichier: %v", err)
+			}
+			defer f.Close()
+			g, err := f.CreateGroup("series")
+			if err != nil {
+				t.Fatalf("création du groupe: %v", err)
+			}
+			defer g.Close()
+			if err := WriteEntry(g, DataEntryFloat{C: "a", V: [][]float64{{1, 1}}}, DefaultOptions()); err != nil {
+				t.Fatalf("écriture de la série: %v", err)
+			}
+			dset, err := g.OpenDataset("a")
+			if err != nil {
+				t.Fatalf("ouverture de 'a': %v", err)
+			}
+			defer dset.Close()
+			names, err := attributeNames(dset)
+			if err != nil {
+				t.Fatalf("lecture des attributs: %v", err)
+			}
+			for _, name := range names {
+				if err := dset.DeleteAttribute(name); err != nil {
+					t.Fatalf("suppression de '%s': %v", name, err)
+				}
+			}
+			for _, name := range tt.existing {
+				if err := writeAttribute(dset, name, "ancien"); err != nil {
+					t.Fatalf("écriture de '%s': %v", name, err)
+				}
+			}
+
+			staged := &stagedAttributes{dset: dset}
+			for _, name := range tt.staged {
+				if err := writeAttribute(staged, name, "nouveau"); err != nil {
+					t.Fatalf("écriture temporaire de '%s': %v", name, err)
+				}
+			}
+			err = staged.commit(tt.oldNames, DefaultOptions())
+			if (err != nil) != tt.fail {
+				t.Fatalf("commit: erreur %v, échec attendu %v", err, tt.fail)
+			}
+
+			names, err = attributeNames(dset)
+			if err != nil {
+				t.Fatalf("lecture des attributs: %v", err)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("attributs %q, attendu %q", names, tt.want)
+			}
+			// Après un échec, les attributs d'origine gardent leur valeur
+			want := "nouveau"
+			if tt.fail {
+				want = "ancien"
+			}
+			if value, err := readAttribute(dset, "l_site"); err != nil || value != want {
+				t.Errorf("l_site: (%v, %v), attendu %s", value, err, want)
+			}
+		})
+	}
+}
+
+// La table d'index est étendue sur place tant que ses champs de longueur fixe suffisent,
+// et recréée sinon
+func TestUpdateIndexTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		second string
+		labels string
+	}{
+		{name: "extension sur place", second: `[[{"c": "b", "v": [[1000, 1]]}]]`, labels: "{}"},
+		{
+			name:   "table recréée",
+			second: `[[{"c": "b", "l": {"description": "libellé bien plus long que ceux de la table"}, "v": [[1000, 1]]}]]`,
+			labels: `{"description":"libellé bien plus long que ceux de la table"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.h5")
+			opts := DefaultOptions()
+			opts.Append = true
+			if _, err := convertFile(t, path, `[[{"c": "a", "v": [[1000, 1]]}]]`, opts); err != nil {
+				t.Fatalf("première conversion: %v", err)
+			}
+			if _, err := convertFile(t, path, tt.second, opts); err != nil {
+				t.Fatalf("ajout: %v", err)
+			}
+
+			f, err := hdf5.OpenFile(path, hdf5.F_ACC_RDONLY)
+			if err != nil {
+				t.Fatalf("ouverture: %v", err)
+			}
+			defer f.Close()
+			index, err := readIndexRows(f)
+			if err != nil {
+				t.Fatalf("lecture de la table d'index: %v", err)
+			}
+			if len(index) != 2 || index[0].Path != "/a" || index[1].Path != "/b" {
+				t.Fatalf("table d'index %+v, attendu /a puis /b", index)
+			}
+			if index[0].Labels != "{}" || index[1].Labels != tt.labels {
+				t.Errorf("labels %q et %q, attendu {} et %q", index[0].Labels, index[1].Labels, tt.labels)
+			}
+		})
+	}
+}
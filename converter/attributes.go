@@ -0,0 +1,239 @@
+package converter
+
+import (
+	"fmt"
+	"reflect"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Objet HDF5 pouvant porter des attributs : *hdf5.File, *hdf5.Group ou *hdf5.Dataset
+type attributeHolder interface {
+	CreateAttribute(name string, dtype *hdf5.Datatype, dspace *hdf5.Dataspace) (*hdf5.Attribute, error)
+}
+
+// Chaîne écrite en attribut de longueur fixe, ASCII, comme le fait PyTables
+// (les attributs chaîne des autres types sont de longueur variable, UTF-8)
+type fixedString string
+
+// Fonction auxiliaire pour ajouter un attribut dont le type HDF5 est déduit de la valeur Go.
+// Les scalaires (entiers signés ou non, flottants, booléens, chaînes) donnent un attribut
+// scalaire, les slices et tableaux un attribut 1-D du type de leurs éléments.
+// La pile d'erreurs HDF5 d'un échec de création ou d'écriture est relevée avant la
+// fermeture des objets (voir stageError).
+func writeAttribute(obj attributeHolder, name string, value interface{}) error {
+	if s, ok := value.(fixedString); ok {
+		return writeFixedStringAttribute(obj, name, string(s))
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return fmt.Errorf("valeur nulle pour l'attribut '%s'", name)
+	}
+
+	// Déterminer le type des éléments et l'espace de données de l'attribut
+	isArray := rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	elemType := rv.Type()
+	if isArray {
+		elemType = elemType.Elem()
+	}
+
+	dtype, err := attributeDatatype(elemType)
+	if err != nil {
+		return fmt.Errorf("attribut '%s': %v", name, err)
+	}
+	defer dtype.Close()
+
+	var dspace *hdf5.Dataspace
+	switch {
+	case !isArray:
+		dspace, err = hdf5.CreateDataspace(hdf5.S_SCALAR)
+	case rv.Len() == 0:
+		dspace, err = hdf5.CreateDataspace(hdf5.S_NULL)
+	default:
+		dspace, err = hdf5.CreateSimpleDataspace([]uint{uint(rv.Len())}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	defer dspace.Close()
+
+	// Créer l'attribut
+	attr, err := obj.CreateAttribute(name, dtype, dspace)
+	if err != nil {
+		return hdf5.CaptureErrorStack(err)
+	}
+	defer attr.Close()
+
+	// Un attribut vide n'a pas de données à écrire
+	if isArray && rv.Len() == 0 {
+		return nil
+	}
+
+	// Écrire la valeur, convertie dans sa représentation mémoire HDF5
+	if isArray {
+		data := reflect.MakeSlice(reflect.SliceOf(attributeValue(reflect.Zero(elemType)).Type()), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			data.Index(i).Set(attributeValue(rv.Index(i)))
+		}
+		return hdf5.CaptureErrorStack(attr.Write(data.Interface(), dtype))
+	}
+	data := reflect.New(attributeValue(rv).Type())
+	data.Elem().Set(attributeValue(rv))
+	return hdf5.CaptureErrorStack(attr.Write(data.Interface(), dtype))
+}
+
+// Fonction auxiliaire pour ajouter un attribut scalaire chaîne de longueur fixe, zéro compris
+func writeFixedStringAttribute(obj attributeHolder, name, value string) error {
+	dtype, err := hdf5.T_C_S1.Copy()
+	if err != nil {
+		return err
+	}
+	defer dtype.Close()
+	if err := dtype.SetSize(len(value) + 1); err != nil {
+		return err
+	}
+
+	dspace, err := hdf5.CreateDataspace(hdf5.S_SCALAR)
+	if err != nil {
+		return err
+	}
+	defer dspace.Close()
+
+	attr, err := obj.CreateAttribute(name, dtype, dspace)
+	if err != nil {
+		return hdf5.CaptureErrorStack(err)
+	}
+	defer attr.Close()
+
+	data := append([]byte(value), 0)
+	return hdf5.CaptureErrorStack(attr.Write(&data[0], dtype))
+}
+
+// Fonction auxiliaire pour obtenir le type HDF5 correspondant à un type Go.
+// Le type retourné doit être fermé par l'appelant.
+func attributeDatatype(t reflect.Type) (*hdf5.Datatype, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return newBoolDatatype()
+	case reflect.Int8:
+		return hdf5.T_NATIVE_INT8.Copy()
+	case reflect.Int16:
+		return hdf5.T_NATIVE_INT16.Copy()
+	case reflect.Int32:
+		return hdf5.T_NATIVE_INT32.Copy()
+	case reflect.Int, reflect.Int64:
+		return hdf5.T_NATIVE_INT64.Copy()
+	case reflect.Uint8:
+		return hdf5.T_NATIVE_UINT8.Copy()
+	case reflect.Uint16:
+		return hdf5.T_NATIVE_UINT16.Copy()
+	case reflect.Uint32:
+		return hdf5.T_NATIVE_UINT32.Copy()
+	case reflect.Uint, reflect.Uint64:
+		return hdf5.T_NATIVE_UINT64.Copy()
+	case reflect.Float32:
+		return hdf5.T_NATIVE_FLOAT.Copy()
+	case reflect.Float64:
+		return hdf5.T_NATIVE_DOUBLE.Copy()
+	case reflect.String:
+		// Chaînes de longueur variable marquées UTF-8 (libellés accentués)
+		return hdf5.T_GO_UTF8_STRING.Copy()
+	default:
+		return nil, fmt.Errorf("type Go non pris en charge pour les attributs: %v", t)
+	}
+}
+
+// Fonction auxiliaire pour créer le type booléen HDF5, une énumération
+// FALSE/TRUE sur un entier 8 bits (représentation utilisée par h5py).
+func newBoolDatatype() (*hdf5.Datatype, error) {
+	enum, err := hdf5.NewEnumType(hdf5.T_NATIVE_INT8)
+	if err != nil {
+		return nil, err
+	}
+	if err := enum.Insert("FALSE", int8(0)); err != nil {
+		enum.Close()
+		return nil, err
+	}
+	if err := enum.Insert("TRUE", int8(1)); err != nil {
+		enum.Close()
+		return nil, err
+	}
+	return &enum.Datatype, nil
+}
+
+// Fonction auxiliaire pour convertir une valeur Go dans la représentation
+// mémoire de son type HDF5 (int et uint sur 64 bits, booléens sur 8 bits)
+func attributeValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return reflect.ValueOf(int8(1))
+		}
+		return reflect.ValueOf(int8(0))
+	case reflect.Int:
+		return reflect.ValueOf(v.Int())
+	case reflect.Uint:
+		return reflect.ValueOf(v.Uint())
+	default:
+		return v
+	}
+}
+
+// Taille estimée d'un message d'attribut dans l'en-tête d'un objet, hors valeur : en-tête
+// du message, type et espace de données
+const attributeMessageBytes = 64
+
+// Taille d'une chaîne de longueur variable dans l'en-tête : une référence au tas global
+const varStringBytes = 16
+
+// Place occupée par les attributs d'un objet dans son en-tête. Tant qu'ils sont peu
+// nombreux (maxCompactAttributes), HDF5 les y stocke, dans la limite de 64 Kio partagée
+// avec les données d'un dataset compact.
+type attributeSize struct {
+	count int // nombre d'attributs
+	bytes int // taille estimée de leurs messages
+}
+
+// Fonction auxiliaire pour compter un attribut de valeur value (voir writeAttribute)
+func (s attributeSize) add(name string, value interface{}) attributeSize {
+	return s.addBytes(name, attributeValueBytes(value))
+}
+
+// Fonction auxiliaire pour compter un attribut dont la valeur occupe size octets
+func (s attributeSize) addBytes(name string, size int) attributeSize {
+	return attributeSize{count: s.count + 1, bytes: s.bytes + attributeMessageBytes + len(name) + size}
+}
+
+// Fonction auxiliaire pour cumuler deux ensembles d'attributs d'un même objet
+func (s attributeSize) plus(other attributeSize) attributeSize {
+	return attributeSize{count: s.count + other.count, bytes: s.bytes + other.bytes}
+}
+
+// Octets des attributs stockés dans l'en-tête de l'objet : aucun en stockage dense
+func (s attributeSize) headerBytes() int {
+	if s.count > maxCompactAttributes {
+		return 0
+	}
+	return s.bytes
+}
+
+// Fonction auxiliaire pour estimer la taille de la valeur d'un attribut dans l'en-tête de
+// l'objet, dans sa représentation HDF5 (voir attributeDatatype)
+func attributeValueBytes(value interface{}) int {
+	if s, ok := value.(fixedString); ok {
+		return len(s) + 1
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return 0
+	}
+	n, elemType := 1, rv.Type()
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		n, elemType = rv.Len(), elemType.Elem()
+	}
+	if elemType.Kind() == reflect.String {
+		return n * varStringBytes
+	}
+	return n * int(attributeValue(reflect.Zero(elemType)).Type().Size())
+}
@@ -0,0 +1,237 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Version des conventions CF déclarée dans l'attribut global "Conventions"
+const cfConventions = "CF-1.8"
+
+// Unité de la coordonnée temps
+const cfTimeUnits = "seconds since 1970-01-01 00:00:00"
+
+// Suffixe du nom de la coordonnée temps d'une série
+const cfTimeSuffix = "_time"
+
+// WriteCFGlobalAttributes écrit les attributs globaux attendus par les outils NetCDF/CF
+// (format cf), à appeler une fois par fichier avant Convert
+func WriteCFGlobalAttributes(f *hdf5.File) error {
+	if err := writeAttribute(f, "Conventions", cfConventions); err != nil {
+		return err
+	}
+	history := fmt.Sprintf("%s: converti depuis JSON par hdf5_test2", time.Now().UTC().Format(time.RFC3339))
+	return writeAttribute(f, "history", history)
+}
+
+// Fonction pour écrire une série selon les conventions CF : une coordonnée temps
+// "<name>_time" (échelle de dimension HDF5) et une variable 1-D par colonne de valeurs,
+// "<name>" ou "<name>_1" à "<name>_<n>", attachée à cette coordonnée.
+// Retourne les variables de valeurs, qui portent ensuite les métadonnées de l'entrée, et
+// les noms des objets créés, y compris en cas d'erreur.
+func writeCFVariables(loc location, name string, entry DataEntryFloat, attrs attributeSize, opts Options) ([]*hdf5.Dataset, []string, hdf5.Layout, error) {
+	rows := len(entry.V)
+	cols := len(entry.V[0])
+
+	space, err := hdf5.CreateSimpleDataspace([]uint{uint(rows)}, nil)
+	if err != nil {
+		return nil, nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de l'espace de données: %w", err)
+	}
+	defer space.Close()
+
+	// Les noms dérivés ne doivent pas désigner un objet existant, d'une autre série
+	for _, objName := range cfObjectNames(name, cols) {
+		if loc.LinkExists(objName) {
+			return nil, nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "le nom '%s' est déjà utilisé dans le fichier", objName)
+		}
+	}
+
+	timeName := name + cfTimeSuffix
+	// Objets créés, à supprimer par l'appelant si l'écriture de la série échoue ; une
+	// variable peut avoir été créée avant l'échec de son écriture
+	var created []string
+	track := func(objName string) {
+		if loc.LinkExists(objName) {
+			created = append(created, objName)
+		}
+	}
+
+	// Coordonnée temps, en secondes (horodatages d'origine en millisecondes), échelle de
+	// dimension des variables de valeurs
+	times := func(i int) float64 { return entry.V[i][0] * opts.TimestampDivisor / 1000 }
+	timeAttributes := []namedAttribute{
+		{Name: "standard_name", Value: "time"},
+		{Name: "long_name", Value: "time"},
+		{Name: "units", Value: cfTimeUnits},
+		{Name: "calendar", Value: "standard"},
+		{Name: "axis", Value: "T"},
+	}
+	// Attributs de l'échelle de dimension (SetScale), et références des variables qui lui
+	// sont attachées (AttachScale)
+	timeAttrs := cfAttributeSize(timeAttributes).
+		add("CLASS", fixedString("DIMENSION_SCALE")).
+		add("NAME", fixedString(timeName)).
+		addBytes("REFERENCE_LIST", cfReferenceBytes*(cols-1))
+	if cols == 1 {
+		// Sans colonne de valeurs, la coordonnée temps porte les métadonnées de l'entrée
+		timeAttrs = timeAttrs.plus(attrs)
+	}
+	timeVar, layout, err := writeCFVariable(loc, timeName, space, rows, times, timeAttrs, opts)
+	track(timeName)
+	if err != nil {
+		return nil, created, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	// En cas d'erreur, les variables déjà créées sont fermées
+	vars := make([]*hdf5.Dataset, 0, cols-1)
+	fail := func(err error) ([]*hdf5.Dataset, []string, hdf5.Layout, error) {
+		timeVar.Close()
+		for _, dset := range vars {
+			dset.Close()
+		}
+		return nil, created, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	if err := timeVar.SetScale(timeName); err != nil {
+		return fail(stageError(StageAttributes, "déclaration de l'échelle de dimension '%s': %w", timeName, err))
+	}
+	for _, attr := range timeAttributes {
+		if err := writeAttribute(timeVar, attr.Name, attr.Value); err != nil {
+			return fail(stageError(StageAttributes, "ajout de l'attribut '%s': %w", attr.Name, err))
+		}
+	}
+
+	// Attributs CF des variables de valeurs, tirés des libellés
+	longName := entry.C
+	if label, ok := entry.L["long_name"].(string); ok && label != "" {
+		longName = label
+	}
+	units := cfUnits(entry)
+
+	for j := 1; j < cols; j++ {
+		varName := cfVariableName(name, cols, j)
+
+		values := func(i int) float64 {
+			if row := entry.V[i]; j < len(row) {
+				return row[j]
+			}
+			return math.NaN()
+		}
+
+		// _FillValue, long_name et units s'ajoutent aux attributs de l'entrée, ainsi que la
+		// référence à la coordonnée temps
+		cfAttributes := []namedAttribute{{Name: "_FillValue", Value: math.NaN()}, {Name: "long_name", Value: longName}}
+		if units != "" {
+			cfAttributes = append(cfAttributes, namedAttribute{Name: "units", Value: units})
+		}
+		varAttrs := cfAttributeSize(cfAttributes).addBytes("DIMENSION_LIST", cfReferenceBytes).plus(attrs)
+		dset, _, err := writeCFVariable(loc, varName, space, rows, values, varAttrs, opts)
+		track(varName)
+		if err != nil {
+			return fail(err)
+		}
+		vars = append(vars, dset)
+
+		for _, attr := range cfAttributes {
+			if err := writeAttribute(dset, attr.Name, attr.Value); err != nil {
+				return fail(stageError(StageAttributes, "ajout de l'attribut '%s': %w", attr.Name, err))
+			}
+		}
+
+		if err := dset.AttachScale(timeVar, 0); err != nil {
+			return fail(stageError(StageAttributes, "attachement de '%s' à '%s': %w", varName, timeName, err))
+		}
+	}
+
+	// Sans colonne de valeurs, la coordonnée temps porte seule les métadonnées
+	if len(vars) == 0 {
+		return []*hdf5.Dataset{timeVar}, created, layout, nil
+	}
+	timeVar.Close()
+	return vars, created, layout, nil
+}
+
+// Taille estimée d'une référence d'objet des attributs d'échelles de dimension
+// (REFERENCE_LIST, DIMENSION_LIST)
+const cfReferenceBytes = 16
+
+// Fonction auxiliaire pour compter les attributs CF d'une variable
+func cfAttributeSize(cfAttributes []namedAttribute) attributeSize {
+	var attrs attributeSize
+	for _, attr := range cfAttributes {
+		attrs = attrs.add(attr.Name, attr.Value)
+	}
+	return attrs
+}
+
+// Fonction auxiliaire pour créer et écrire une variable CF 1-D de rows float64 portant les
+// attributs attrs, value(i) donnant la valeur de la ligne i, avec NaN comme valeur de
+// remplissage. Les valeurs sont écrites par blocs de lignes.
+func writeCFVariable(loc location, name string, space *hdf5.Dataspace, rows int, value func(i int) float64, attrs attributeSize, opts Options) (*hdf5.Dataset, hdf5.Layout, error) {
+	chunks := []uint{uint(rows)}
+	prop, layout, err := newDatasetPropList(rows, 1, chunks, attrs, opts)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+	defer prop.Close()
+
+	if err := prop.SetFillValue(hdf5.T_NATIVE_DOUBLE, math.NaN()); err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "configuration de la valeur de remplissage: %w", err)
+	}
+
+	dset, err := loc.CreateDatasetWith(name, hdf5.T_NATIVE_DOUBLE, space, prop)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du dataset '%s': %w", name, err)
+	}
+	block := alignBlockRows(blockRows(rows, opts), chunks)
+	buf := make([]float64, block)
+	err = writeBlocks(rows, block, func(start, n int) error {
+		data := buf[:n]
+		for i := range data {
+			data[i] = value(start + i)
+		}
+		return writeRowsSubset(dset, uint(start), uint(n), 0, &data)
+	})
+	if err != nil {
+		dset.Close() // pile d'erreurs relevée par writeRowsSubset
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+	return dset, layout, nil
+}
+
+// Fonction auxiliaire pour trouver l'unité d'une série dans ses libellés ("units" ou "unit")
+// ou dans ses champs supplémentaires ("u" ou "units")
+func cfUnits(entry DataEntryFloat) string {
+	for _, key := range []string{"units", "unit"} {
+		if units, ok := entry.L[key].(string); ok && units != "" {
+			return units
+		}
+	}
+	for _, key := range []string{"u", "units"} {
+		if units, ok := entry.X[key].(string); ok && units != "" {
+			return units
+		}
+	}
+	return ""
+}
+
+// Fonction auxiliaire pour lister les noms des objets d'une série au format CF : la
+// coordonnée temps puis les variables de valeurs
+func cfObjectNames(name string, cols int) []string {
+	names := []string{name + cfTimeSuffix}
+	for j := 1; j < cols; j++ {
+		names = append(names, cfVariableName(name, cols, j))
+	}
+	return names
+}
+
+// Fonction auxiliaire pour nommer la variable CF de la colonne de valeurs j (j ≥ 1)
+func cfVariableName(name string, cols, j int) string {
+	if cols > 2 {
+		return fmt.Sprintf("%s_%d", name, j)
+	}
+	return name
+}
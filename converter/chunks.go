@@ -0,0 +1,198 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Écriture directe des chunks : les chunks d'une matrice sont passés en parallèle par le
+// pipeline de filtres du dataset (shuffle puis deflate, appliqués en Go comme le ferait la
+// bibliothèque) et transmis tels quels à HDF5 par H5Dwrite_chunk. Les fichiers restent
+// lisibles par tout lecteur HDF5, les filtres étant les filtres standard.
+
+// Identifiants des filtres HDF5 appliqués par le programme
+const (
+	filterDeflate = 1 // H5Z_FILTER_DEFLATE
+	filterShuffle = 2 // H5Z_FILTER_SHUFFLE
+)
+
+// Taille d'un élément float64 des matrices
+const float64Size = 8
+
+// Filtre du pipeline d'un dataset
+type chunkFilter struct {
+	id    int
+	param int // niveau de compression (deflate) ou taille des éléments (shuffle)
+}
+
+// Chunk filtré, prêt à être écrit
+type encodedChunk struct {
+	offset []uint
+	data   []byte
+	err    error
+}
+
+// Fonction pour écrire un bloc de lignes d'une matrice (data, en ordre ligne par ligne, de
+// dimensions dims, à partir de la ligne rowOffset du dataset, multiple des chunks) chunk
+// par chunk, en compressant les chunks dans workers goroutines ; les appels HDF5 restent
+// dans le goroutine appelant. Retourne false, sans rien écrire, si le pipeline du dataset
+// contient un filtre que le programme ne sait pas appliquer.
+func writeChunksDirect(dset *hdf5.Dataset, data []float64, dims, chunks []uint, rowOffset uint, workers int) (bool, error) {
+	filters, ok, err := datasetFilters(dset)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// Grille des chunks ; les chunks de bord sont complétés par la valeur de remplissage (0)
+	gridRows := int((dims[0] + chunks[0] - 1) / chunks[0])
+	gridCols := int((dims[1] + chunks[1] - 1) / chunks[1])
+	count := gridRows * gridCols
+
+	done := make(chan struct{})
+	defer close(done)
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for k := 0; k < count; k++ {
+			select {
+			case indices <- k:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	encoded := make(chan encodedChunk, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range indices {
+				offset := []uint{uint(k/gridCols) * chunks[0], uint(k%gridCols) * chunks[1]}
+				chunk := encodeChunk(data, dims, chunks, offset, filters)
+				select {
+				case encoded <- chunk:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(encoded)
+	}()
+
+	// Les chunks sont écrits dans l'ordre où ils sont prêts : leur position est explicite
+	for chunk := range encoded {
+		if chunk.err != nil {
+			return false, chunk.err
+		}
+		offset := []uint{rowOffset + chunk.offset[0], chunk.offset[1]}
+		if err := dset.WriteChunk(offset, 0, chunk.data); err != nil {
+			return false, fmt.Errorf("chunk %v: %w", offset, err)
+		}
+	}
+	return true, nil
+}
+
+// Fonction auxiliaire pour lire le pipeline de filtres d'un dataset ; retourne false si
+// un filtre ne peut pas être appliqué par le programme
+func datasetFilters(dset *hdf5.Dataset) ([]chunkFilter, bool, error) {
+	prop, err := dset.CreatePropList()
+	if err != nil {
+		return nil, false, fmt.Errorf("lecture des propriétés du dataset: %w", err)
+	}
+	defer prop.Close()
+
+	var filters []chunkFilter
+	for i := 0; i < prop.NumFilters(); i++ {
+		filter, err := prop.Filter(i)
+		if err != nil {
+			return nil, false, err
+		}
+		switch filter.ID {
+		case filterDeflate:
+			level := 6 // niveau par défaut de zlib
+			if len(filter.Params) > 0 {
+				level = int(filter.Params[0])
+			}
+			filters = append(filters, chunkFilter{id: filterDeflate, param: level})
+		case filterShuffle:
+			size := float64Size
+			if len(filter.Params) > 0 && filter.Params[0] > 0 {
+				size = int(filter.Params[0])
+			}
+			filters = append(filters, chunkFilter{id: filterShuffle, param: size})
+		default:
+			return nil, false, nil
+		}
+	}
+	return filters, true, nil
+}
+
+// Fonction pour extraire le chunk commençant à offset et lui appliquer les filtres
+func encodeChunk(data []float64, dims, chunks, offset []uint, filters []chunkFilter) encodedChunk {
+	rows, cols := int(chunks[0]), int(chunks[1])
+	buf := make([]byte, rows*cols*float64Size)
+	for i := 0; i < rows; i++ {
+		row := int(offset[0]) + i
+		if row >= int(dims[0]) {
+			break
+		}
+		for j := 0; j < cols; j++ {
+			col := int(offset[1]) + j
+			if col >= int(dims[1]) {
+				break
+			}
+			value := data[row*int(dims[1])+col]
+			binary.NativeEndian.PutUint64(buf[(i*cols+j)*float64Size:], math.Float64bits(value))
+		}
+	}
+
+	for _, filter := range filters {
+		switch filter.id {
+		case filterShuffle:
+			buf = shuffleBytes(buf, filter.param)
+		case filterDeflate:
+			var compressed bytes.Buffer
+			w, err := zlib.NewWriterLevel(&compressed, filter.param)
+			if err != nil {
+				return encodedChunk{err: fmt.Errorf("compression du chunk %v: %w", offset, err)}
+			}
+			if _, err := w.Write(buf); err != nil {
+				return encodedChunk{err: fmt.Errorf("compression du chunk %v: %w", offset, err)}
+			}
+			if err := w.Close(); err != nil {
+				return encodedChunk{err: fmt.Errorf("compression du chunk %v: %w", offset, err)}
+			}
+			buf = compressed.Bytes()
+		}
+	}
+	return encodedChunk{offset: offset, data: buf}
+}
+
+// Fonction auxiliaire pour regrouper les octets des éléments de taille size par rang,
+// comme le filtre shuffle de HDF5 (les octets restants sont recopiés à la fin)
+func shuffleBytes(src []byte, size int) []byte {
+	if size <= 1 {
+		return src
+	}
+	n := len(src) / size
+	dst := make([]byte, len(src))
+	for i := 0; i < n; i++ {
+		for b := 0; b < size; b++ {
+			dst[b*n+i] = src[i*size+b]
+		}
+	}
+	copy(dst[n*size:], src[n*size:])
+	return dst
+}
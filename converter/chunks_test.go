@@ -0,0 +1,148 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Regroupement des octets par rang, comme le filtre shuffle de HDF5
+func TestShuffleBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []byte
+		size int
+		want []byte
+	}{
+		{name: "éléments de 2 octets", src: []byte{1, 2, 3, 4, 5, 6}, size: 2, want: []byte{1, 3, 5, 2, 4, 6}},
+		{name: "éléments de 4 octets", src: []byte{1, 2, 3, 4, 5, 6, 7, 8}, size: 4, want: []byte{1, 5, 2, 6, 3, 7, 4, 8}},
+		{name: "octets restants recopiés", src: []byte{1, 2, 3, 4, 9}, size: 2, want: []byte{1, 3, 2, 4, 9}},
+		{name: "éléments d'un octet", src: []byte{1, 2, 3}, size: 1, want: []byte{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shuffleBytes(tt.src, tt.size); !bytes.Equal(got, tt.want) {
+				t.Errorf("shuffleBytes(%v, %d) = %v, attendu %v", tt.src, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+// Les chunks filtrés en Go et écrits directement sont ceux que produit le pipeline de
+// filtres de HDF5 (chunks de bord compris) ; la compression deflate pouvant différer
+// d'une implémentation de zlib à l'autre, les chunks compressés sont comparés décompressés
+func TestWriteChunksDirectMatchesLibrary(t *testing.T) {
+	dims := []uint{5, 3}
+	chunks := []uint{2, 2}
+	data := make([]float64, dims[0]*dims[1])
+	for i := range data {
+		data[i] = float64(i) + 0.25
+	}
+
+	tests := []struct {
+		name    string
+		shuffle bool
+		deflate int
+	}{
+		{name: "sans filtre"},
+		{name: "shuffle", shuffle: true},
+		{name: "deflate", deflate: 6},
+		{name: "shuffle et deflate", shuffle: true, deflate: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "chunks.h5"), hdf5.F_ACC_TRUNC)
+			if err != nil {
+				t.Fatalf("création du fichier: %v", err)
+			}
+			defer f.Close()
+
+			create := func(name string) *hdf5.Dataset {
+				t.Helper()
+				space, err := hdf5.CreateSimpleDataspace(dims, nil)
+				if err != nil {
+					t.Fatalf("création de l'espace de données: %v", err)
+				}
+				defer space.Close()
+				prop, err := hdf5.NewPropList(hdf5.P_DATASET_CREATE)
+				if err != nil {
+					t.Fatalf("création de la liste de propriétés: %v", err)
+				}
+				defer prop.Close()
+				if err := prop.SetChunk(chunks); err != nil {
+					t.Fatalf("configuration du chunking: %v", err)
+				}
+				opts := DefaultOptions()
+				opts.Shuffle = tt.shuffle
+				opts.Compression = tt.deflate
+				setCompression(prop, opts)
+				dset, err := f.CreateDatasetWith(name, hdf5.T_NATIVE_DOUBLE, space, prop)
+				if err != nil {
+					t.Fatalf("création du dataset '%s': %v", name, err)
+				}
+				t.Cleanup(func() { dset.Close() })
+				return dset
+			}
+
+			library := create("library")
+			if err := library.Write(&data); err != nil {
+				t.Fatalf("écriture par la bibliothèque: %v", err)
+			}
+			direct := create("direct")
+			written, err := writeChunksDirect(direct, data, dims, chunks, 0, 2)
+			if err != nil || !written {
+				t.Fatalf("écriture directe: (%v, %v), attendu (true, nil)", written, err)
+			}
+
+			for row := uint(0); row < dims[0]; row += chunks[0] {
+				for col := uint(0); col < dims[1]; col += chunks[1] {
+					offset := []uint{row, col}
+					want := readRawChunk(t, library, offset, tt.deflate > 0)
+					got := readRawChunk(t, direct, offset, tt.deflate > 0)
+					if !bytes.Equal(got, want) {
+						t.Errorf("chunk %v: %v, attendu %v", offset, got, want)
+					}
+				}
+			}
+
+			// Relus par HDF5, à travers ses filtres
+			read := make([]float64, len(data))
+			if err := direct.Read(&read); err != nil {
+				t.Fatalf("lecture: %v", err)
+			}
+			if !reflect.DeepEqual(read, data) {
+				t.Errorf("données relues %v, attendu %v", read, data)
+			}
+		})
+	}
+}
+
+// Fonction auxiliaire pour lire un chunk tel qu'il est stocké, décompressé si inflate
+func readRawChunk(t *testing.T, dset *hdf5.Dataset, offset []uint, inflate bool) []byte {
+	t.Helper()
+	data, mask, err := dset.ReadChunk(offset)
+	if err != nil {
+		t.Fatalf("lecture du chunk %v: %v", offset, err)
+	}
+	if mask != 0 {
+		t.Errorf("chunk %v: masque de filtres %#x, attendu 0", offset, mask)
+	}
+	if !inflate {
+		return data
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("décompression du chunk %v: %v", offset, err)
+	}
+	defer r.Close()
+	data, err = io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("décompression du chunk %v: %v", offset, err)
+	}
+	return data
+}
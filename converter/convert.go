@@ -0,0 +1,314 @@
+// Package converter convertit les séries JSON ([][]DataEntryRaw) en fichier HDF5, et relit
+// les fichiers produits (export, vérification, inspection). Il est utilisé par la ligne de
+// commande hdf5_test2 et peut être intégré à d'autres programmes : les erreurs sont
+// retournées, jamais fatales.
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Report résume une conversion
+type Report struct {
+	Entries         int                 // séries écrites ou prolongées
+	Empty           int                 // entrées sans données, ignorées
+	Warnings        int                 // valeurs de V non converties (remplacées par 0.0)
+	ChannelWarnings []ChannelWarnings   // valeurs non converties, par série et par type
+	Layouts         map[hdf5.Layout]int // datasets créés, par type de stockage
+	Extended        int                 // séries existantes prolongées (mode ajout)
+	AddedRows       int                 // lignes ajoutées aux séries existantes
+	SkippedRows     int                 // lignes déjà présentes, ignorées
+	Failures        []Failure           // séries en échec, ignorées (mode keep-going)
+}
+
+// Progress décrit l'avancement d'une conversion (voir Options.Progress)
+type Progress struct {
+	BytesRead int64  // octets du JSON source lus, lecture anticipée comprise
+	BytesDone int64  // octets du JSON source jusqu'à la fin de la dernière entrée traitée
+	Entries   int    // entrées traitées (écrites, vides ou en échec)
+	Rows      int64  // lignes écrites ou ajoutées
+	Channel   string // série de la dernière entrée traitée
+}
+
+// Convert lit le JSON source depuis r et écrit ses séries à la racine de f, suivies de la
+// table d'index (sauf au format mat). La création du fichier, ses attributs globaux
+// (WriteCFGlobalAttributes, WritePandasGlobalAttributes) et l'en-tête MATLAB restent à la
+// charge de l'appelant. En cas d'erreur, le rapport décrit ce qui a déjà été écrit ; les
+// erreurs d'une série sont de type *EntryError. En mode keep-going (opts.KeepGoing), une
+// série en échec (entrée illisible ou rejetée en mode strict, écriture impossible) est
+// retirée du fichier et décrite dans report.Failures, et la conversion continue ; une
+// série existante dont l'ajout échoue est conservée. Seule une erreur de syntaxe du JSON
+// source interrompt alors la conversion. L'annulation de ctx interrompt la conversion
+// entre deux séries.
+//
+// Le JSON est lu au fil de l'eau : opts.Workers goroutines décodent et prétraitent les
+// entrées, qui sont écrites dans l'ordre du fichier source par le goroutine appelant, seul
+// à utiliser f. Une erreur de syntaxe JSON est donc signalée après l'écriture des séries
+// qui la précèdent.
+func Convert(ctx context.Context, r io.Reader, f *hdf5.File, opts Options) (Report, error) {
+	report := Report{Layouts: make(map[hdf5.Layout]int)}
+	if err := opts.Validate(); err != nil {
+		return report, err
+	}
+
+	// Les piles d'erreurs HDF5 sont propres à chaque thread : le goroutine qui écrit les
+	// séries reste sur le même thread pour les relever après un échec (stageError)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Lignes d'index du fichier existant, mises à jour à la fin de l'ajout
+	var existingIndex []indexRow
+	if opts.Append {
+		var err error
+		existingIndex, err = readIndexRows(f)
+		if err != nil {
+			return report, fmt.Errorf("lecture de la table d'index: %w", err)
+		}
+	}
+	existingRows := make(map[string]indexRow, len(existingIndex))
+	for _, row := range existingIndex {
+		existingRows[row.Path] = row
+	}
+
+	// Lignes de la table d'index global, une par série écrite
+	var index []indexRow
+
+	// Garder une trace des noms de datasets déjà utilisés, par tableau extérieur (le nom
+	// de la table d'index est réservé)
+	var datasetNames map[string]int
+	currentOuter := -1
+
+	// Lignes écrites, pour le suivi de l'avancement
+	var rowsWritten int64
+
+	// Fonction pour écrire une entrée décodée et prétraitée
+	writeItem := func(item pipelineResult) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if item.err != nil {
+			// Entrée illisible ou rejetée en mode strict : rien n'a été écrit. Seules les
+			// erreurs de syntaxe du JSON source (lecture) interrompent toujours la conversion.
+			var entryErr *EntryError
+			if !opts.KeepGoing || !errors.As(item.err, &entryErr) {
+				return item.err
+			}
+			report.Failures = append(report.Failures, newFailure(entryErr))
+			return nil
+		}
+		datasetIndex, entryIndex, entry := item.outerIndex, item.entryIndex, item.entry
+		if datasetIndex != currentOuter {
+			datasetNames = map[string]int{IndexTableName: 0}
+			currentOuter = datasetIndex
+		}
+
+		// Vérifier qu'il y a des données à stocker
+		if len(entry.V) == 0 {
+			report.Empty++
+			return nil // Passer à l'entrée suivante si aucune donnée
+		}
+
+		// Vérifier si le nom existe déjà et générer un nom unique
+		baseName := entry.C
+		uniqueName := entryDatasetName(datasetNames, entry, opts)
+		if len(entry.WarningCounts) > 0 {
+			report.ChannelWarnings = append(report.ChannelWarnings, ChannelWarnings{Channel: baseName, Path: "/" + uniqueName, Counts: entry.WarningCounts})
+		}
+
+		// En mode ajout, prolonger la série si elle existe déjà
+		if opts.Append && f.LinkExists(uniqueName) {
+			result, err := appendEntry(f, uniqueName, baseName, entry, opts)
+			if err != nil {
+				entryErr := entryError(err, StageAppend, baseName, "/"+uniqueName, datasetIndex, entryIndex)
+				if !opts.KeepGoing {
+					return entryErr
+				}
+				// Le dataset existant est conservé tel quel (lignes et attributs), ainsi
+				// que sa ligne d'index lors de la fusion
+				report.Failures = append(report.Failures, newFailure(entryErr))
+				return nil
+			}
+			report.Entries++
+			report.Warnings += entry.Warnings
+			report.Extended++
+			report.AddedRows += result.Added
+			report.SkippedRows += result.Skipped
+			rowsWritten += int64(result.Added)
+			opts.logger().Debug("Série prolongée", "event", "entry_extended", "channel", baseName, "path", "/"+uniqueName,
+				"outer_index", datasetIndex, "entry_index", entryIndex, "added_rows", result.Added, "skipped_rows", result.Skipped)
+
+			// La ligne d'index décrit tout le dataset et garde sa position d'origine
+			path := "/" + uniqueName
+			row := newIndexRow(path, datasetIndex, entryIndex, entry)
+			if old, ok := existingRows[path]; ok {
+				row.OuterIndex, row.EntryIndex = old.OuterIndex, old.EntryIndex
+				row.Warnings += old.Warnings
+			}
+			row.Rows, row.Cols = int64(result.Rows), int64(result.Cols)
+			row.FirstTimestamp, row.LastTimestamp = result.FirstTimestamp, result.LastTimestamp
+			index = append(index, row)
+			return nil
+		}
+
+		// Écrire le dataset et ses attributs
+		layout, path, created, err := writeEntry(f, uniqueName, baseName, entry, opts)
+		if err != nil {
+			entryErr := entryError(err, StageDataset, baseName, "/"+uniqueName, datasetIndex, entryIndex)
+			if !opts.KeepGoing {
+				return entryErr
+			}
+			// Ne pas laisser de série à moitié écrite dans le fichier
+			failure := newFailure(entryErr)
+			if err := removeEntry(f, created); err != nil {
+				failure.CleanupError = err.Error()
+			}
+			report.Failures = append(report.Failures, failure)
+			return nil
+		}
+		report.Entries++
+		report.Warnings += entry.Warnings
+		report.Layouts[layout]++
+
+		rowsWritten += int64(len(entry.V))
+		opts.logger().Debug("Série écrite", "event", "entry_written", "channel", baseName, "path", path,
+			"outer_index", datasetIndex, "entry_index", entryIndex, "rows", len(entry.V), "layout", layout.String())
+
+		index = append(index, newIndexRow(path, datasetIndex, entryIndex, entry))
+		return nil
+	}
+
+	// Les entrées sont décodées et prétraitées en parallèle, puis écrites une à une dans
+	// l'ordre du JSON source
+	input := &countingReader{r: r}
+	processed := 0
+	err := runPipeline(ctx, input, opts, func(item pipelineResult) error {
+		if err := writeItem(item); err != nil {
+			return err
+		}
+		processed++
+		if opts.Progress != nil {
+			opts.Progress(Progress{BytesRead: input.n.Load(), BytesDone: item.end, Entries: processed, Rows: rowsWritten, Channel: item.entry.C})
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Écrire l'index global des séries (pas en mode mat : MATLAB n'accepte à la racine
+	// que des variables portant l'attribut MATLAB_class)
+	if opts.Format == FormatMat {
+		return report, nil
+	}
+	// En mode ajout, la table existante est mise à jour avec la table fusionnée
+	if opts.Append {
+		if err := updateIndexTable(f, mergeIndexRows(existingIndex, index)); err != nil {
+			return report, fmt.Errorf("mise à jour de la table d'index: %w", err)
+		}
+		return report, nil
+	}
+	if err := writeIndexTable(f, index); err != nil {
+		return report, fmt.Errorf("écriture de la table d'index: %w", err)
+	}
+	return report, nil
+}
+
+// WriteEntry écrit une série déjà prétraitée (voir Preprocess) dans le groupe g, sous son
+// nom "c" (rendu valide pour MATLAB au format mat). En mode ajout, une série existante est
+// prolongée. Les erreurs sont de type *EntryError.
+func WriteEntry(g *hdf5.Group, entry DataEntryFloat, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	runtime.LockOSThread() // piles d'erreurs HDF5 (voir Convert)
+	defer runtime.UnlockOSThread()
+
+	name := entry.C
+	if opts.Format == FormatMat {
+		name = matlabIdentifier(name)
+	}
+	if len(entry.V) == 0 {
+		return entryError(errors.New("aucune donnée à écrire"), StageDataset, entry.C, name, -1, -1)
+	}
+
+	if opts.Append && g.LinkExists(name) {
+		if _, err := appendEntry(g, name, entry.C, entry, opts); err != nil {
+			return entryError(err, StageAppend, entry.C, name, -1, -1)
+		}
+		return nil
+	}
+	if _, _, _, err := writeEntry(g, name, entry.C, entry, opts); err != nil {
+		return entryError(err, StageDataset, entry.C, name, -1, -1)
+	}
+	return nil
+}
+
+// UniqueDatasetName génère un nom de dataset unique à partir du nom de la série :
+// les doublons reçoivent un suffixe _1, _2..., en sautant les noms déjà pris par une autre
+// série (datasetNames compte les noms déjà utilisés)
+func UniqueDatasetName(datasetNames map[string]int, baseName, format string) string {
+	name := baseName
+	if format == FormatMat {
+		// Les noms de variables MATLAB sont restreints
+		name = matlabIdentifier(baseName)
+	}
+	count, exists := datasetNames[name]
+
+	if !exists {
+		// Premier dataset avec ce nom
+		datasetNames[name] = 0
+		return name
+	}
+
+	// Incrémenter le compteur et l'utiliser comme suffixe
+	for {
+		count++
+		candidate := fmt.Sprintf("%s_%d", name, count)
+		if format == FormatMat {
+			candidate = truncateMatlabName(name, fmt.Sprintf("_%d", count))
+		}
+		if _, taken := datasetNames[candidate]; taken {
+			continue
+		}
+		datasetNames[name] = count
+		datasetNames[candidate] = 0
+		return candidate
+	}
+}
+
+// Fonction auxiliaire pour choisir le nom d'une série : au format CF, le nom retenu est
+// celui dont la coordonnée temps et les variables de valeurs sont toutes libres, et ces
+// noms sont réservés pour les séries suivantes
+func entryDatasetName(datasetNames map[string]int, entry DataEntryFloat, opts Options) string {
+	for {
+		name := UniqueDatasetName(datasetNames, entry.C, opts.Format)
+		if opts.Format != FormatCF {
+			return name
+		}
+		names := cfObjectNames(name, len(entry.V[0]))
+		free := true
+		for _, objName := range names {
+			if objName == name {
+				continue
+			}
+			if _, taken := datasetNames[objName]; taken {
+				free = false
+			}
+		}
+		if !free {
+			continue
+		}
+		for _, objName := range names {
+			if _, taken := datasetNames[objName]; !taken {
+				datasetNames[objName] = 0
+			}
+		}
+		return name
+	}
+}
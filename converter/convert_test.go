@@ -0,0 +1,176 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Fonction auxiliaire pour convertir un JSON source dans un nouveau fichier HDF5 temporaire,
+// fermé à la fin du test
+func convertString(t *testing.T, input string, opts Options) (*hdf5.File, Report, error) {
+	t.Helper()
+	f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "test.h5"), hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatalf("création du fichier: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	report, err := Convert(context.Background(), strings.NewReader(input), f, opts)
+	return f, report, err
+}
+
+// Une entrée illisible ou rejetée en mode strict est ignorée en mode keep-going : les
+// autres séries sont écrites et l'échec est décrit dans le rapport
+func TestConvertKeepGoingEntryErrors(t *testing.T) {
+	const (
+		good1 = `{"c": "a", "v": [[1000, 1]]}`
+		good2 = `{"c": "b", "v": [[1000, 2]]}`
+	)
+	tests := []struct {
+		name   string
+		bad    string
+		strict func(*Options)
+		stage  string
+		row    int
+		col    int
+	}{
+		{name: "la hors plage", bad: `{"c": "bad", "la": 300, "v": [[1000, 3]]}`, stage: StageDecode, row: -1, col: -1},
+		{name: "v mal typé", bad: `{"c": "bad", "v": {"t": 1000}}`, stage: StageDecode, row: -1, col: -1},
+		{
+			name:   "champ inconnu, mode strict",
+			bad:    `{"c": "bad", "extra": 1, "v": [[1000, 3]]}`,
+			strict: func(opts *Options) { opts.StrictFields = true },
+			stage:  StageDecode, row: -1, col: -1,
+		},
+		{
+			name:   "valeur non convertie, mode strict",
+			bad:    `{"c": "bad", "v": [[1000, "x"]]}`,
+			strict: func(opts *Options) { opts.StrictValues = true },
+			stage:  StageValues, row: 0, col: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "[[" + good1 + ", " + tt.bad + ", " + good2 + "]]"
+			opts := DefaultOptions()
+			if tt.strict != nil {
+				tt.strict(&opts)
+			}
+
+			// Sans keep-going, l'entrée interrompt la conversion
+			_, _, err := convertString(t, input, opts)
+			var entryErr *EntryError
+			if !errors.As(err, &entryErr) {
+				t.Fatalf("sans keep-going: erreur %v, attendu *EntryError", err)
+			}
+			if entryErr.Stage != tt.stage {
+				t.Errorf("sans keep-going: étape %q, attendu %q", entryErr.Stage, tt.stage)
+			}
+
+			opts.KeepGoing = true
+			f, report, err := convertString(t, input, opts)
+			if err != nil {
+				t.Fatalf("keep-going: %v", err)
+			}
+			if report.Entries != 2 {
+				t.Errorf("keep-going: %d séries écrites, attendu 2", report.Entries)
+			}
+			if len(report.Failures) != 1 {
+				t.Fatalf("keep-going: %d échecs, attendu 1", len(report.Failures))
+			}
+			failure := report.Failures[0]
+			if failure.Stage != tt.stage || failure.OuterIndex != 0 || failure.EntryIndex != 1 ||
+				failure.Row != tt.row || failure.Col != tt.col {
+				t.Errorf("keep-going: échec %+v, attendu étape %q en [0][1], ligne %d, colonne %d",
+					failure, tt.stage, tt.row, tt.col)
+			}
+			for _, name := range []string{"a", "b"} {
+				if !f.LinkExists(name) {
+					t.Errorf("keep-going: série '%s' absente du fichier", name)
+				}
+			}
+			if f.LinkExists("bad") {
+				t.Errorf("keep-going: série en échec présente dans le fichier")
+			}
+			rows, err := readIndexRows(f)
+			if err != nil {
+				t.Fatalf("lecture de la table d'index: %v", err)
+			}
+			if len(rows) != 2 {
+				t.Errorf("table d'index: %d lignes, attendu 2", len(rows))
+			}
+		})
+	}
+}
+
+// Une erreur de syntaxe du JSON source interrompt la conversion, même en mode keep-going
+func TestConvertKeepGoingSyntaxError(t *testing.T) {
+	opts := DefaultOptions()
+	opts.KeepGoing = true
+	_, report, err := convertString(t, `[[{"c": "a", "v": [[1000, 1]]}, {"c": `, opts)
+	if err == nil {
+		t.Fatalf("erreur de syntaxe non signalée")
+	}
+	var entryErr *EntryError
+	if errors.As(err, &entryErr) {
+		t.Errorf("erreur de syntaxe rapportée comme erreur de série: %v", err)
+	}
+	if report.Entries != 1 {
+		t.Errorf("%d séries écrites avant l'erreur, attendu 1", report.Entries)
+	}
+}
+
+// Une série dont l'écriture échoue après la création de ses objets (attribut trop grand
+// pour l'en-tête du dataset) est retirée du fichier en mode keep-going, variables CF
+// comprises
+func TestConvertKeepGoingCleanup(t *testing.T) {
+	// Plus de 64 Kio de métadonnées dans un seul attribut, en stockage compact
+	values := make([]string, 10000)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	bad := `{"c": "bad", "l": {"big": [` + strings.Join(values, ", ") + `]}, "v": [[1000, 1, 2]]}`
+	input := `[[{"c": "a", "v": [[1000, 1, 2]]}, ` + bad + `, {"c": "b", "v": [[1000, 3, 4]]}]]`
+
+	for _, format := range []string{FormatMatrix, FormatCompound, FormatCF} {
+		t.Run(format, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Format = format
+			opts.KeepGoing = true
+			f, report, err := convertString(t, input, opts)
+			if err != nil {
+				t.Fatalf("conversion: %v", err)
+			}
+			if report.Entries != 2 || len(report.Failures) != 1 {
+				t.Fatalf("%d séries écrites, échecs %+v, attendu 2 séries et un échec", report.Entries, report.Failures)
+			}
+			failure := report.Failures[0]
+			if failure.Stage != StageAttributes || failure.CleanupError != "" {
+				t.Errorf("échec %+v, attendu étape %q sans erreur de suppression", failure, StageAttributes)
+			}
+
+			names := []string{"bad"}
+			if format == FormatCF {
+				names = cfObjectNames("bad", 3)
+			}
+			for _, name := range names {
+				if f.LinkExists(name) {
+					t.Errorf("objet '%s' de la série en échec laissé dans le fichier", name)
+				}
+			}
+			rows, err := readIndexRows(f)
+			if err != nil {
+				t.Fatalf("lecture de la table d'index: %v", err)
+			}
+			if len(rows) != 2 {
+				t.Errorf("table d'index: %d lignes, attendu 2", len(rows))
+			}
+		})
+	}
+}
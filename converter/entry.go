@@ -0,0 +1,183 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Structure pour représenter une entrée de données avant traitement de conversion en float64
+type DataEntryRaw struct {
+	C  string                 `json:"c"`
+	L  map[string]interface{} `json:"l"`
+	A  map[string]interface{} `json:"a"`
+	La uint8                  `json:"la"`
+	V  [][]interface{}        `json:"v"`
+	X  map[string]interface{} `json:"-"` // champs inconnus, conservés tels quels
+}
+
+// Structure pour représenter une entrée de données après traitement de conversion en float64
+type DataEntryFloat struct {
+	C  string                 `json:"c"`
+	L  map[string]interface{} `json:"l"`
+	A  map[string]interface{} `json:"a"`
+	La uint8                  `json:"la"`
+	V  [][]float64            `json:"v"`
+	X  map[string]interface{} `json:"-"`
+
+	Warnings      int            `json:"-"` // nombre de valeurs de V non converties
+	WarningCounts []WarningCount `json:"-"` // valeurs non converties, par type
+}
+
+// Fonction auxiliaire pour convertir les différents types de données en float64.
+// Retourne le type d'avertissement (WarningNull...) si la valeur n'a pas pu être
+// convertie (0.0 est alors utilisé), "" sinon.
+func convertToFloat64(val interface{}) (float64, string) {
+	switch v := val.(type) {
+	case nil:
+		return 0, WarningNull
+	case float64:
+		return v, ""
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return 0, WarningUnparseableNumber // valeur par défaut
+		}
+		return parsed, ""
+	case bool:
+		if v {
+			return 1.0, ""
+		}
+		return 0.0, ""
+	/*case int64:
+	return float64(v)*/
+	case string:
+		// Tenter de convertir la chaîne en nombre si possible
+		if val == "true" || v == "TRUE" || v == "True" {
+			return 1.0, ""
+		} else if val == "false" || v == "FALSE" || v == "False" {
+			return 0.0, ""
+			// S3P.Activity
+		} else if val == "R" { // Début de la période de repos "rest"
+			return 1, ""
+		} else if val == "r" { // repos
+			return 0, ""
+		} else if val == "D" { // Début de période de conduite "driving"
+			return 7, ""
+		} else if val == "d" { // conduite
+			return 6, ""
+		} else if val == "W" { // Début de la période de travail "working"
+			return 5, ""
+		} else if val == "w" { // travail
+			return 4, ""
+		} else if val == "A" { // Début de la période de disponibilité "available"
+			return 3, ""
+		} else if val == "a" { // disponibilité
+			return 2, ""
+			// S3P.Ignition
+		} else if val == "ON" { // ignition on
+			return 1, ""
+		} else if val == "OFF" { // ignition off
+			return 0, ""
+		} else {
+			// Tentative de conversion en float
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, WarningUnparseableString // valeur par défaut
+			}
+			return parsed, ""
+		}
+	default:
+		return 0, WarningUnsupportedType // valeur par défaut
+	}
+}
+
+// Preprocess pré-traite les données JSON et convertit toutes les valeurs V en float64 :
+// lignes remises dans l'ordre chronologique et horodatages divisés selon opts. Les valeurs
+// non converties sont remplacées par 0.0 et comptées dans WarningCounts ; avec
+// opts.StrictValues, la première d'entre elles est une erreur de type *EntryError.
+func Preprocess(rawDatasets [][]DataEntryRaw, opts Options) ([][]DataEntryFloat, error) {
+	processedDatasets := make([][]DataEntryFloat, len(rawDatasets))
+
+	for datasetIndex, rawDataset := range rawDatasets {
+		processedDataset := make([]DataEntryFloat, len(rawDataset))
+
+		for entryIndex, rawEntry := range rawDataset {
+			processedEntry, err := preprocessEntry(rawEntry, datasetIndex, entryIndex, opts)
+			if err != nil {
+				return nil, err
+			}
+			processedDataset[entryIndex] = processedEntry
+		}
+
+		processedDatasets[datasetIndex] = processedDataset
+	}
+
+	return processedDatasets, nil
+}
+
+// Fonction pour pré-traiter une entrée, à la position [datasetIndex][entryIndex] du JSON
+// (voir Preprocess)
+func preprocessEntry(rawEntry DataEntryRaw, datasetIndex, entryIndex int, opts Options) (DataEntryFloat, error) {
+	// Créer une entrée avec les mêmes valeurs sauf pour V
+	processedEntry := DataEntryFloat{
+		C:  rawEntry.C,
+		L:  rawEntry.L,
+		A:  rawEntry.A,
+		La: rawEntry.La,
+		X:  rawEntry.X,
+	}
+
+	// Traiter la matrice V
+	if len(rawEntry.V) > 0 {
+		rows := len(rawEntry.V)
+		cols := len(rawEntry.V[0])
+
+		processedV := make([][]float64, rows)
+		for i := 0; i < rows; i++ {
+			processedV[i] = make([]float64, cols)
+			for j := 0; j < cols; j++ {
+				if j < len(rawEntry.V[i]) { // Protection contre les lignes de longueurs différentes
+					value, kind := convertToFloat64(rawEntry.V[i][j])
+					if kind != "" {
+						example := warningExample(rawEntry.V[i][j], i, j)
+						if opts.StrictValues {
+							return processedEntry, &EntryError{
+								Channel:    rawEntry.C,
+								OuterIndex: datasetIndex,
+								EntryIndex: entryIndex,
+								Row:        i,
+								Col:        j,
+								Stage:      StageValues,
+								Err:        fmt.Errorf("valeur non convertie (%s): %s", kind, example),
+							}
+						}
+						processedEntry.Warnings++
+						processedEntry.WarningCounts = addWarning(processedEntry.WarningCounts, kind, example)
+					}
+					processedV[i][j] = value
+				}
+			}
+		}
+
+		processedEntry.V = processedV
+		// Inverser l'ordre des lignes
+		//rows1 := len(processedEntry.V)
+		if opts.ReverseRows {
+			for i := 0; i < rows/2; i++ {
+				processedEntry.V[i], processedEntry.V[rows-i-1] = processedEntry.V[rows-i-1], processedEntry.V[i]
+			}
+		}
+
+		// Diviser les TS (par 1000 par défaut)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				if j == 0 {
+					processedEntry.V[i][j] = processedEntry.V[i][j] / opts.TimestampDivisor
+				}
+			}
+		}
+	}
+
+	return processedEntry, nil
+}
@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Conversion des valeurs JSON en float64, et type d'avertissement des valeurs non converties
+func TestConvertToFloat64(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want float64
+		kind string
+	}{
+		{name: "flottant", val: 1.5, want: 1.5},
+		{name: "nombre JSON", val: json.Number("42"), want: 42},
+		{name: "nombre JSON hors plage", val: json.Number("1e400"), kind: WarningUnparseableNumber},
+		{name: "booléen vrai", val: true, want: 1},
+		{name: "booléen faux", val: false, want: 0},
+		{name: "chaîne numérique", val: "2.5", want: 2.5},
+		{name: "chaîne true", val: "True", want: 1},
+		{name: "chaîne false", val: "FALSE", want: 0},
+		{name: "état conduite", val: "D", want: 7},
+		{name: "état disponibilité", val: "a", want: 2},
+		{name: "contact", val: "ON", want: 1},
+		{name: "chaîne illisible", val: "abc", kind: WarningUnparseableString},
+		{name: "null", val: nil, kind: WarningNull},
+		{name: "tableau", val: []interface{}{1.0}, kind: WarningUnsupportedType},
+		{name: "objet", val: map[string]interface{}{"x": 1.0}, kind: WarningUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, kind := convertToFloat64(tt.val)
+			if got != tt.want || kind != tt.kind {
+				t.Errorf("convertToFloat64(%#v) = (%v, %q), attendu (%v, %q)", tt.val, got, kind, tt.want, tt.kind)
+			}
+		})
+	}
+}
+
+// Les valeurs non converties sont comptées par type, dans l'ordre de leur première
+// apparition ; en mode strict, la première est une erreur située dans l'entrée
+func TestPreprocessEntryWarnings(t *testing.T) {
+	raw := DataEntryRaw{C: "a", V: [][]interface{}{
+		{json.Number("1000"), nil, "x"},
+		{json.Number("2000"), nil, []interface{}{}},
+		{json.Number("3000"), nil, "y"},
+		{json.Number("4000"), nil},
+	}}
+
+	entry, err := preprocessEntry(raw, 0, 0, DefaultOptions())
+	if err != nil {
+		t.Fatalf("pré-traitement: %v", err)
+	}
+	if entry.Warnings != 7 {
+		t.Errorf("%d valeurs non converties, attendu 7", entry.Warnings)
+	}
+	// La ligne courte est complétée sans avertissement ; seuls les premiers exemples
+	// sont conservés
+	want := []WarningCount{
+		{Kind: WarningNull, Count: 4, Examples: []string{"[0][1] null", "[1][1] null", "[2][1] null"}},
+		{Kind: WarningUnparseableString, Count: 2, Examples: []string{`[0][2] "x"`, `[2][2] "y"`}},
+		{Kind: WarningUnsupportedType, Count: 1, Examples: []string{"[1][2] []"}},
+	}
+	if !reflect.DeepEqual(entry.WarningCounts, want) {
+		t.Errorf("avertissements %+v, attendu %+v", entry.WarningCounts, want)
+	}
+
+	opts := DefaultOptions()
+	opts.StrictValues = true
+	_, err = preprocessEntry(raw, 2, 3, opts)
+	var entryErr *EntryError
+	if !errors.As(err, &entryErr) {
+		t.Fatalf("mode strict: erreur %v, attendu *EntryError", err)
+	}
+	if entryErr.Stage != StageValues || entryErr.OuterIndex != 2 || entryErr.EntryIndex != 3 ||
+		entryErr.Row != 0 || entryErr.Col != 1 {
+		t.Errorf("mode strict: erreur %+v, attendu étape %q en [2][3], ligne 0, colonne 1", entryErr, StageValues)
+	}
+}
@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Étapes de l'écriture d'une série, rapportées par EntryError
+const (
+	StageDataset    = "dataset"    // création du dataset (espace, type, propriétés)
+	StageWrite      = "write"      // écriture des données
+	StageAttributes = "attributes" // écriture des attributs
+	StageAppend     = "append"     // prolongement d'un dataset existant
+	StageValues     = "values"     // conversion des valeurs de V (mode strict)
+	StageDecode     = "decode"     // décodage de l'entrée JSON, ou schéma rejeté (mode strict)
+)
+
+// ErrUnsupportedFormat est retourné par l'export et la vérification pour un fichier, ou
+// une série, écrit dans un format qu'ils ne relisent pas (cf, pandas)
+var ErrUnsupportedFormat = errors.New("format non pris en charge (formats matrix, compound et mat)")
+
+// EntryError est l'erreur retournée pour une série qui n'a pas pu être écrite
+type EntryError struct {
+	Channel    string // nom "c" de la série
+	Path       string // nom du dataset dans le fichier
+	OuterIndex int    // position dans le tableau JSON extérieur (-1 si inconnue)
+	EntryIndex int    // position dans le tableau JSON intérieur (-1 si inconnue)
+	Row        int    // ligne de V de la valeur en échec (-1 si sans objet)
+	Col        int    // colonne de V de la valeur en échec (-1 si sans objet)
+	Stage      string // étape en échec : StageDataset, StageWrite, StageAttributes, StageAppend, StageValues ou StageDecode
+	Err        error
+}
+
+func (e *EntryError) Error() string {
+	msg := fmt.Sprintf("série '%s'", e.Channel)
+	if e.OuterIndex >= 0 && e.EntryIndex >= 0 {
+		msg += fmt.Sprintf(" [%d][%d]", e.OuterIndex, e.EntryIndex)
+	}
+	if e.Stage != "" {
+		msg += " (" + e.Stage + ")"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// HDF5Stack retourne la pile d'erreurs HDF5 enregistrée lors de l'échec, ou nil si
+// l'erreur ne vient pas de la bibliothèque HDF5
+func (e *EntryError) HDF5Stack() []string {
+	return hdf5.ErrorStack(e.Err)
+}
+
+// Failure décrit une série ignorée en mode keep-going, sous une forme prête à être
+// encodée en JSON
+type Failure struct {
+	Channel      string   `json:"channel"`
+	Path         string   `json:"path"`
+	OuterIndex   int      `json:"outer_index"`
+	EntryIndex   int      `json:"entry_index"`
+	Row          int      `json:"row"`
+	Col          int      `json:"col"`
+	Stage        string   `json:"stage"`
+	Error        string   `json:"error"`
+	HDF5Stack    []string `json:"hdf5_stack,omitempty"`
+	CleanupError string   `json:"cleanup_error,omitempty"` // échec de la suppression de l'objet partiel
+}
+
+// Fonction auxiliaire pour décrire l'échec d'une série
+func newFailure(err *EntryError) Failure {
+	return Failure{
+		Channel:    err.Channel,
+		Path:       err.Path,
+		OuterIndex: err.OuterIndex,
+		EntryIndex: err.EntryIndex,
+		Row:        err.Row,
+		Col:        err.Col,
+		Stage:      err.Stage,
+		Error:      err.Err.Error(),
+		HDF5Stack:  err.HDF5Stack(),
+	}
+}
+
+// Fonction auxiliaire pour créer l'erreur d'une étape ; la série est renseignée par l'appelant.
+// La pile d'erreurs HDF5 des erreurs en argument est relevée à cet instant : stageError doit
+// être appelée juste après l'appel en échec, avant tout autre appel à la bibliothèque (voir
+// Convert).
+func stageError(stage, format string, args ...interface{}) error {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			hdf5.CaptureErrorStack(err)
+		}
+	}
+	return &EntryError{OuterIndex: -1, EntryIndex: -1, Row: -1, Col: -1, Stage: stage, Err: fmt.Errorf(format, args...)}
+}
+
+// Fonction auxiliaire pour compléter une erreur avec le contexte de la série ; les erreurs
+// sans étape reçoivent l'étape stage
+func entryError(err error, stage, channel, path string, outerIndex, entryIndex int) *EntryError {
+	var entryErr *EntryError
+	if !errors.As(err, &entryErr) {
+		entryErr = &EntryError{Row: -1, Col: -1, Stage: stage, Err: err}
+	}
+	entryErr.Channel, entryErr.Path = channel, path
+	entryErr.OuterIndex, entryErr.EntryIndex = outerIndex, entryIndex
+	return entryErr
+}
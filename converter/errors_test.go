@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Un échec de la bibliothèque HDF5 est rapporté avec sa pile d'erreurs, relevée sur le
+// thread de l'appel en échec ; une erreur de la bibliothèque sans EntryError n'en a pas
+func TestEntryErrorHDF5Stack(t *testing.T) {
+	f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "stack.h5"), hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatalf("création du fichier: %v", err)
+	}
+	defer f.Close()
+	g, err := f.CreateGroup("series")
+	if err != nil {
+		t.Fatalf("création du groupe: %v", err)
+	}
+	defer g.Close()
+
+	entry := DataEntryFloat{C: "a", V: [][]float64{{1, 2}}}
+	if err := WriteEntry(g, entry, DefaultOptions()); err != nil {
+		t.Fatalf("première écriture: %v", err)
+	}
+
+	// Le dataset existe déjà : la création échoue dans la bibliothèque
+	err = WriteEntry(g, entry, DefaultOptions())
+	var entryErr *EntryError
+	if !errors.As(err, &entryErr) {
+		t.Fatalf("seconde écriture: erreur %v, attendu *EntryError", err)
+	}
+	if entryErr.Stage != StageDataset {
+		t.Errorf("étape %q, attendu %q", entryErr.Stage, StageDataset)
+	}
+	if len(entryErr.HDF5Stack()) == 0 {
+		t.Errorf("pile d'erreurs HDF5 absente")
+	}
+
+	// Échec attendu, traité par l'appelant : la pile n'est pas relevée
+	_, err = g.OpenDataset("absent")
+	if err == nil {
+		t.Fatalf("ouverture d'un dataset absent sans erreur")
+	}
+	if stack := hdf5.ErrorStack(err); stack != nil {
+		t.Errorf("pile d'erreurs relevée sans CaptureErrorStack: %q", stack)
+	}
+}
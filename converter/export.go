@@ -0,0 +1,364 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Entrée exportée, avec sa position dans le JSON d'origine
+type exportedEntry struct {
+	Position indexPosition
+	Indexed  bool // position lue dans la table d'index
+	Entry    DataEntryRaw
+}
+
+// ExportFile reconstruit les entrées de toutes les séries de la racine du fichier, en
+// annulant le prétraitement décrit par opts (formats matrix, compound et mat).
+// L'ordre d'origine est retrouvé grâce à la table d'index ; sans index, toutes les séries
+// sont placées dans un seul tableau, par ordre alphabétique. Retourne aussi le nombre d'entrées.
+// Un fichier aux formats cf ou pandas, ou un objet de la racine dans aucun des formats
+// relus, donne une erreur ErrUnsupportedFormat.
+func ExportFile(f *hdf5.File, opts Options) ([][]DataEntryRaw, int, error) {
+	if err := checkReadableFormat(f, opts.Format); err != nil {
+		return nil, 0, err
+	}
+	positions, err := readIndexPositions(f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n, err := f.NumObjects()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var entries []exportedEntry
+	outerCount := 0
+	for i := uint(0); i < n; i++ {
+		name, err := f.ObjectNameByIndex(i)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ, err := f.ObjectTypeByIndex(i)
+		if err != nil {
+			return nil, 0, err
+		}
+		if name == IndexTableName {
+			continue
+		}
+		if typ != hdf5.H5G_DATASET {
+			return nil, 0, fmt.Errorf("objet '/%s': %w", name, ErrUnsupportedFormat)
+		}
+
+		entry, ok, err := exportDataset(f, name, opts)
+		if err != nil {
+			return nil, 0, fmt.Errorf("dataset '%s': %v", name, err)
+		}
+		if !ok {
+			return nil, 0, fmt.Errorf("dataset '/%s': %w", name, ErrUnsupportedFormat)
+		}
+
+		position, indexed := positions["/"+name]
+		if indexed {
+			outerCount = max(outerCount, position.OuterIndex+1)
+		}
+		entries = append(entries, exportedEntry{Position: position, Indexed: indexed, Entry: entry})
+	}
+
+	// Les séries absentes de l'index sont ajoutées à la fin du dernier tableau
+	if outerCount == 0 {
+		outerCount = 1
+	}
+	for i := range entries {
+		if !entries[i].Indexed {
+			entries[i].Position = indexPosition{OuterIndex: outerCount - 1, EntryIndex: math.MaxInt}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Position, entries[j].Position
+		if a.OuterIndex != b.OuterIndex {
+			return a.OuterIndex < b.OuterIndex
+		}
+		return a.EntryIndex < b.EntryIndex
+	})
+
+	datasets := make([][]DataEntryRaw, outerCount)
+	for i := range datasets {
+		datasets[i] = []DataEntryRaw{}
+	}
+	for _, e := range entries {
+		datasets[e.Position.OuterIndex] = append(datasets[e.Position.OuterIndex], e.Entry)
+	}
+	return datasets, len(entries), nil
+}
+
+// Fonction auxiliaire pour rejeter un fichier que l'export et la vérification ne relisent
+// pas : format cf ou pandas demandé, ou reconnu aux attributs globaux du fichier
+func checkReadableFormat(f *hdf5.File, format string) error {
+	switch {
+	case format == FormatCF || format == FormatPandas:
+		return fmt.Errorf("format %s: %w", format, ErrUnsupportedFormat)
+	case f.AttributeExists("Conventions"):
+		return fmt.Errorf("fichier au format %s: %w", FormatCF, ErrUnsupportedFormat)
+	case f.AttributeExists("PYTABLES_FORMAT_VERSION"):
+		return fmt.Errorf("fichier au format %s: %w", FormatPandas, ErrUnsupportedFormat)
+	}
+	return nil
+}
+
+// Fonction pour reconstruire une entrée à partir d'un dataset de la racine, en annulant
+// le prétraitement décrit par opts. Retourne false si le dataset n'est pas dans un
+// format pris en charge.
+func exportDataset(f *hdf5.File, name string, opts Options) (DataEntryRaw, bool, error) {
+	entry := DataEntryRaw{
+		C: name,
+		L: make(map[string]interface{}),
+		A: make(map[string]interface{}),
+	}
+
+	dset, err := f.OpenDataset(name)
+	if err != nil {
+		return entry, false, err
+	}
+	defer dset.Close()
+
+	rows, ok, err := readEntryRows(dset, opts.TimestampDivisor)
+	if err != nil || !ok {
+		return entry, ok, err
+	}
+
+	// Annuler le prétraitement : ordre des lignes inversé, horodatages divisés
+	entry.V = make([][]interface{}, len(rows))
+	for i, row := range rows {
+		values := make([]interface{}, len(row))
+		for j, value := range row {
+			switch {
+			case j == 0:
+				values[j] = int64(math.Round(value * opts.TimestampDivisor))
+			case math.IsNaN(value) || math.IsInf(value, 0):
+				values[j] = nil // non représentable en JSON
+			default:
+				values[j] = value
+			}
+		}
+		if opts.ReverseRows {
+			entry.V[len(rows)-1-i] = values
+		} else {
+			entry.V[i] = values
+		}
+	}
+
+	if err := readEntryAttributes(dset, &entry); err != nil {
+		return entry, false, err
+	}
+	return entry, true, nil
+}
+
+// Fonction auxiliaire pour lire les lignes d'une série telles qu'elles sont stockées
+// (horodatage prétraité en colonne 0), quel que soit son format : matrice 2-D de
+// float64, variable MATLAB (matrice transposée) ou enregistrements composés (t, value...),
+// dont les horodatages entiers ont été multipliés par timestampDivisor à l'écriture.
+// Retourne false si le dataset n'est dans aucun de ces formats.
+func readEntryRows(dset *hdf5.Dataset, timestampDivisor float64) ([][]float64, bool, error) {
+	dtype, err := dset.Datatype()
+	if err != nil {
+		return nil, false, err
+	}
+	defer dtype.Close()
+
+	space := dset.Space()
+	if space == nil {
+		return nil, false, fmt.Errorf("espace de données inaccessible")
+	}
+	defer space.Close()
+	dims, _, err := space.SimpleExtentDims()
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case dtype.Class() == hdf5.T_FLOAT && dtype.Size() == 8 && len(dims) == 2:
+		data := make([]float64, dims[0]*dims[1])
+		if len(data) > 0 {
+			if err := dset.Read(&data); err != nil {
+				return nil, false, err
+			}
+		}
+
+		// Les variables MATLAB sont stockées par colonnes (cols×rows)
+		rowCount, cols := int(dims[0]), int(dims[1])
+		transposed := dset.AttributeExists("MATLAB_class")
+		if transposed {
+			rowCount, cols = cols, rowCount
+		}
+		rows := make([][]float64, rowCount)
+		for i := range rows {
+			rows[i] = make([]float64, cols)
+			for j := range rows[i] {
+				if transposed {
+					rows[i][j] = data[j*rowCount+i]
+				} else {
+					rows[i][j] = data[i*cols+j]
+				}
+			}
+		}
+		return rows, true, nil
+
+	case dtype.Class() == hdf5.T_COMPOUND && len(dims) == 1:
+		layout, buf, n, err := readDatasetRecords(dset)
+		if err != nil {
+			return nil, false, err
+		}
+		if layout.fieldIndex("t") != 0 {
+			return nil, false, nil
+		}
+		rows := make([][]float64, n)
+		for i := range rows {
+			record := buf[i*layout.Size:]
+			rows[i] = make([]float64, len(layout.Fields))
+			for j := range layout.Fields {
+				switch value := layout.get(record, j).(type) {
+				case int64:
+					rows[i][j] = float64(value) / timestampDivisor
+				case float64:
+					rows[i][j] = value
+				default:
+					return nil, false, nil
+				}
+			}
+		}
+		return rows, true, nil
+	}
+	return nil, false, nil
+}
+
+// Fonction auxiliaire pour relire les attributs d'une entrée : original_name, la, et les
+// métadonnées l_*, a_*, x_* (ou, à défaut, l'attribut composé "meta"). Les clés aplaties
+// sont reconstituées en objets imbriqués ; les tables d'états ajoutées par le
+// convertisseur sont retirées de "a".
+func readEntryAttributes(dset *hdf5.Dataset, entry *DataEntryRaw) error {
+	names, err := attributeNames(dset)
+	if err != nil {
+		return err
+	}
+
+	hasMetadata := false
+	for _, name := range names {
+		section := metadataSection(entry, name)
+		if name != "original_name" && name != "la" && section == nil {
+			continue
+		}
+
+		value, err := readAttribute(dset, name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case name == "original_name":
+			if s, ok := value.(string); ok {
+				entry.C = s
+			}
+		case name == "la":
+			if la, ok := value.(int64); ok {
+				entry.La = uint8(la)
+			}
+		default:
+			if *section == nil {
+				*section = make(map[string]interface{})
+			}
+			setMetadata(*section, name[2:], decodeMetadataValue(value))
+			hasMetadata = true
+		}
+	}
+
+	if !hasMetadata && dset.AttributeExists(metadataRecordName) {
+		if err := readMetadataRecord(dset, entry); err != nil {
+			return err
+		}
+	}
+
+	removeStateAttributes(entry)
+	return nil
+}
+
+// Fonction auxiliaire pour trouver la section ("l", "a" ou "x") d'un attribut de métadonnée
+func metadataSection(entry *DataEntryRaw, name string) *map[string]interface{} {
+	switch {
+	case strings.HasPrefix(name, "l_"):
+		return &entry.L
+	case strings.HasPrefix(name, "a_"):
+		return &entry.A
+	case strings.HasPrefix(name, "x_"):
+		return &entry.X
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour relire les métadonnées de l'attribut composé "meta", dont les
+// valeurs sont stockées en texte (metadataText)
+func readMetadataRecord(dset *hdf5.Dataset, entry *DataEntryRaw) error {
+	layout, buf, n, err := readAttributeRecords(dset, metadataRecordName)
+	if err != nil {
+		return err
+	}
+	source, key, text := layout.fieldIndex("source"), layout.fieldIndex("key"), layout.fieldIndex("value")
+	if source < 0 || key < 0 || text < 0 {
+		return fmt.Errorf("attribut '%s': champs source, key ou value absents", metadataRecordName)
+	}
+
+	for i := 0; i < n; i++ {
+		record := buf[i*layout.Size:]
+		section := metadataSection(entry, layout.get(record, source).(string)+"_")
+		if section == nil {
+			continue
+		}
+		if *section == nil {
+			*section = make(map[string]interface{})
+		}
+		setMetadata(*section, layout.get(record, key).(string), metadataFromText(layout.get(record, text).(string)))
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour relire une valeur écrite par metadataText : le texte précédé
+// de jsonValuePrefix et le texte JSON valide sont décodés, le reste conservé en chaîne
+func metadataFromText(text string) interface{} {
+	if strings.HasPrefix(text, jsonValuePrefix) {
+		return decodeMetadataValue(text)
+	}
+	var decoded interface{}
+	if err := decodeJSON([]byte(text), &decoded); err == nil {
+		return decoded
+	}
+	return text
+}
+
+// Fonction auxiliaire pour placer une valeur dans une map de métadonnées, en reconstituant
+// les objets imbriqués aplatis avec metadataSeparator
+func setMetadata(metadata map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, metadataSeparator)
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := metadata[part].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			metadata[part] = sub
+		}
+		metadata = sub
+	}
+	metadata[parts[len(parts)-1]] = value
+}
+
+// Fonction auxiliaire pour retirer de "a" les tables d'états ajoutées par addStateAttributes
+func removeStateAttributes(entry *DataEntryRaw) {
+	added := DataEntryFloat{C: entry.C}
+	addStateAttributes(&added)
+	for key, value := range added.A {
+		if fmt.Sprint(entry.A[key]) == fmt.Sprint(value) {
+			delete(entry.A, key)
+		}
+	}
+}
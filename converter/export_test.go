@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Entrées relues à l'identique par l'export, métadonnées imbriquées, mixtes ou nulles
+// comprises, quel que soit le format et le stockage des métadonnées
+func TestExportRoundTrip(t *testing.T) {
+	const input = `[[` +
+		`{"c": "a", "l": {"site": "nord", "code": "123", "tags": ["x", "y"], "mixed": [1, "x"], "none": null,` +
+		` "vehicle": {"type": "bus", "axles": 2}}, "a": {"k": 1.5, "empty": {}}, "la": 2, "v": [[2000, 2.5], [1000, 1]]},` +
+		`{"c": "b", "l": {}, "a": {}, "la": 0, "v": [[1000, 3, 4]]}` +
+		`], [` +
+		`{"c": "c", "l": {"prefixed": "json:1"}, "a": {}, "la": 1, "v": [[1000, 5]]}` +
+		`]]`
+
+	tests := []struct {
+		name    string
+		options func(*Options)
+	}{
+		{name: "matrix"},
+		{name: "compound", options: func(opts *Options) { opts.Format = FormatCompound }},
+		{name: "mat", options: func(opts *Options) { opts.Format = FormatMat }},
+		{name: "attribut meta", options: func(opts *Options) {
+			opts.MetadataInAttributes = false
+			opts.MetadataInRecord = true
+		}},
+		{name: "lignes dans l'ordre", options: func(opts *Options) { opts.ReverseRows = false }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tt.options != nil {
+				tt.options(&opts)
+			}
+			f, _, err := convertString(t, input, opts)
+			if err != nil {
+				t.Fatalf("conversion: %v", err)
+			}
+
+			datasets, count, err := ExportFile(f, opts)
+			if err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			if count != 3 {
+				t.Errorf("%d entrées exportées, attendu 3", count)
+			}
+
+			var want [][]DataEntryRaw
+			if err := decodeJSON([]byte(input), &want); err != nil {
+				t.Fatalf("décodage: %v", err)
+			}
+			got, err := json.Marshal(datasets)
+			if err != nil {
+				t.Fatalf("encodage: %v", err)
+			}
+			wantJSON, err := json.Marshal(want)
+			if err != nil {
+				t.Fatalf("encodage: %v", err)
+			}
+			if string(got) != string(wantJSON) {
+				t.Errorf("export:\n%s\nattendu:\n%s", got, wantJSON)
+			}
+		})
+	}
+}
+
+// Les fichiers aux formats cf et pandas sont rejetés par l'export
+func TestExportUnsupportedFormat(t *testing.T) {
+	const input = `[[{"c": "a", "v": [[1000, 1]]}]]`
+	tests := []struct {
+		name   string
+		format string // format de la conversion
+		export string // format déclaré à l'export
+	}{
+		{name: "fichier cf", format: FormatCF, export: FormatMatrix},
+		{name: "fichier pandas", format: FormatPandas, export: FormatMatrix},
+		{name: "format cf demandé", format: FormatMatrix, export: FormatCF},
+		{name: "format pandas demandé", format: FormatMatrix, export: FormatPandas},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Format = tt.format
+			f, _, err := convertString(t, input, opts)
+			if err != nil {
+				t.Fatalf("conversion: %v", err)
+			}
+			opts.Format = tt.export
+			if _, _, err := ExportFile(f, opts); !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("export: erreur %v, attendu ErrUnsupportedFormat", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/hdf5"
+)
+
+// IndexTableName est le nom de la table d'index global, à la racine du fichier ; il est
+// réservé et n'est jamais attribué à une série
+const IndexTableName = "index"
+
+// Ligne de la table d'index : une par série écrite dans le fichier
+type indexRow struct {
+	Path           string  // chemin du dataset dans le fichier
+	C              string  // nom "c" d'origine
+	OuterIndex     int64   // position dans le tableau JSON extérieur
+	EntryIndex     int64   // position dans le tableau JSON intérieur
+	Rows           int64   // nombre de lignes
+	Cols           int64   // nombre de colonnes
+	FirstTimestamp float64 // premier horodatage (colonne 0, après conversion)
+	LastTimestamp  float64 // dernier horodatage
+	Labels         string  // labels "l" en JSON
+	Warnings       int64   // nombre d'avertissements de conversion
+}
+
+// Fonction auxiliaire pour construire la ligne d'index d'une entrée écrite
+func newIndexRow(path string, outerIndex, entryIndex int, entry DataEntryFloat) indexRow {
+	row := indexRow{
+		Path:           path,
+		C:              entry.C,
+		OuterIndex:     int64(outerIndex),
+		EntryIndex:     int64(entryIndex),
+		Rows:           int64(len(entry.V)),
+		FirstTimestamp: math.NaN(),
+		LastTimestamp:  math.NaN(),
+		Labels:         "{}",
+		Warnings:       int64(entry.Warnings),
+	}
+	if len(entry.V) > 0 && len(entry.V[0]) > 0 {
+		row.Cols = int64(len(entry.V[0]))
+		row.FirstTimestamp = entry.V[0][0]
+		row.LastTimestamp = entry.V[len(entry.V)-1][0]
+	}
+	if len(entry.L) > 0 {
+		row.Labels = jsonText(entry.L)
+	}
+	return row
+}
+
+// Fonction auxiliaire pour décrire l'enregistrement de l'index ; les champs chaînes
+// sont dimensionnés sur les plus longues valeurs des lignes
+func indexLayout(rows []indexRow) *recordLayout {
+	var pathLen, cLen, labelsLen int
+	for _, row := range rows {
+		pathLen = max(pathLen, len(row.Path))
+		cLen = max(cLen, len(row.C))
+		labelsLen = max(labelsLen, len(row.Labels))
+	}
+	return newRecordLayout(
+		stringField("path", pathLen),
+		stringField("c", cLen),
+		int64Field("outer_index"),
+		int64Field("entry_index"),
+		int64Field("rows"),
+		int64Field("cols"),
+		float64Field("first_timestamp"),
+		float64Field("last_timestamp"),
+		stringField("labels", labelsLen),
+		int64Field("warnings"),
+	)
+}
+
+// Fonction pour écrire la table d'index global (table de paquets HDF5 /index)
+func writeIndexTable(f *hdf5.File, rows []indexRow) error {
+	layout := indexLayout(rows)
+	dtype, err := layout.datatype()
+	if err != nil {
+		return err
+	}
+	defer dtype.Close()
+
+	table, err := f.CreateTable(IndexTableName, &dtype.Datatype, 64, 6)
+	if err != nil {
+		return err
+	}
+	defer table.Close()
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	fields := make([]int, len(indexFieldNames))
+	for i := range fields {
+		fields[i] = i
+	}
+	records := make([]interface{}, len(rows))
+	for i, row := range rows {
+		record := make([]byte, layout.Size)
+		encodeIndexRow(layout, fields, record, row)
+		records[i] = packedRecord(record)
+	}
+	return table.Append(records...)
+}
+
+// Fonction pour mettre à jour la table d'index d'un fichier existant (mode ajout) avec les
+// lignes fusionnées : la table est étendue aux nouvelles séries et ses lignes réécrites sur
+// place. Si une chaîne ne tient plus dans les champs de la table, celle-ci est recréée.
+func updateIndexTable(f *hdf5.File, rows []indexRow) error {
+	if !f.LinkExists(IndexTableName) {
+		return writeIndexTable(f, rows)
+	}
+
+	dset, err := f.OpenDataset(IndexTableName)
+	if err != nil {
+		return err
+	}
+	layout, fields, err := indexTableLayout(dset)
+	if err != nil {
+		dset.Close()
+		return err
+	}
+
+	if !indexRowsFit(layout, fields, rows) {
+		dset.Close()
+		if err := f.Unlink(IndexTableName); err != nil {
+			return fmt.Errorf("suppression de la table d'index: %v", err)
+		}
+		return writeIndexTable(f, rows)
+	}
+	defer dset.Close()
+
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := dset.SetExtent([]uint{uint(len(rows))}); err != nil {
+		return fmt.Errorf("extension de la table d'index: %v", err)
+	}
+	buf := make([]byte, len(rows)*layout.Size)
+	for i, row := range rows {
+		encodeIndexRow(layout, fields, buf[i*layout.Size:], row)
+	}
+	return writeRowsSubset(dset, 0, uint(len(rows)), 0, &buf)
+}
+
+// Noms des champs de la table d'index, dans l'ordre des champs de indexRow
+var indexFieldNames = []string{"path", "c", "outer_index", "entry_index", "rows", "cols",
+	"first_timestamp", "last_timestamp", "labels", "warnings"}
+
+// Fonction auxiliaire pour encoder une ligne d'index dans record ; fields donne l'indice
+// dans layout de chaque champ de indexFieldNames
+func encodeIndexRow(layout *recordLayout, fields []int, record []byte, row indexRow) {
+	for i, value := range []interface{}{
+		row.Path, row.C, row.OuterIndex, row.EntryIndex, row.Rows, row.Cols,
+		row.FirstTimestamp, row.LastTimestamp, row.Labels, row.Warnings,
+	} {
+		layout.set(record, fields[i], value)
+	}
+}
+
+// Fonction auxiliaire pour décrire l'enregistrement de la table d'index d'un fichier et
+// trouver l'indice de chacun de ses champs (indexFieldNames)
+func indexTableLayout(dset *hdf5.Dataset) (*recordLayout, []int, error) {
+	dtype, err := dset.Datatype()
+	if err != nil {
+		return nil, nil, err
+	}
+	layout, err := recordLayoutOf(dtype)
+	dtype.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("table '%s': %v", IndexTableName, err)
+	}
+	fields := make([]int, len(indexFieldNames))
+	for i, name := range indexFieldNames {
+		if fields[i] = layout.fieldIndex(name); fields[i] < 0 {
+			return nil, nil, fmt.Errorf("table '%s': champ '%s' absent", IndexTableName, name)
+		}
+	}
+	return layout, fields, nil
+}
+
+// Fonction auxiliaire pour vérifier que les chaînes des lignes tiennent dans les champs
+// de longueur fixe de la table d'index (zéro final compris)
+func indexRowsFit(layout *recordLayout, fields []int, rows []indexRow) bool {
+	for _, row := range rows {
+		for i, value := range map[int]string{0: row.Path, 1: row.C, 8: row.Labels} {
+			if len(value) >= layout.Fields[fields[i]].Size {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Fonction pour relire les lignes de la table d'index d'un fichier existant (mode ajout).
+// Retourne une liste vide si le fichier n'a pas de table d'index.
+func readIndexRows(f *hdf5.File) ([]indexRow, error) {
+	if !f.LinkExists(IndexTableName) {
+		return nil, nil
+	}
+
+	dset, err := f.OpenDataset(IndexTableName)
+	if err != nil {
+		return nil, err
+	}
+	defer dset.Close()
+
+	layout, buf, n, err := readDatasetRecords(dset)
+	if err != nil {
+		return nil, fmt.Errorf("table '%s': %v", IndexTableName, err)
+	}
+	fields := make([]int, len(indexFieldNames))
+	for i, name := range indexFieldNames {
+		if fields[i] = layout.fieldIndex(name); fields[i] < 0 {
+			return nil, fmt.Errorf("table '%s': champ '%s' absent", IndexTableName, name)
+		}
+	}
+
+	rows := make([]indexRow, n)
+	for i := range rows {
+		record := buf[i*layout.Size:]
+		rows[i] = indexRow{
+			Path:           layout.get(record, fields[0]).(string),
+			C:              layout.get(record, fields[1]).(string),
+			OuterIndex:     layout.get(record, fields[2]).(int64),
+			EntryIndex:     layout.get(record, fields[3]).(int64),
+			Rows:           layout.get(record, fields[4]).(int64),
+			Cols:           layout.get(record, fields[5]).(int64),
+			FirstTimestamp: layout.get(record, fields[6]).(float64),
+			LastTimestamp:  layout.get(record, fields[7]).(float64),
+			Labels:         layout.get(record, fields[8]).(string),
+			Warnings:       layout.get(record, fields[9]).(int64),
+		}
+	}
+	return rows, nil
+}
+
+// Fonction auxiliaire pour fusionner les lignes d'index d'un fichier existant avec celles
+// des séries écrites ou prolongées : une ligne remplace celle de même chemin, les
+// nouvelles séries sont ajoutées à la fin
+func mergeIndexRows(existing, updated []indexRow) []indexRow {
+	positions := make(map[string]int, len(existing))
+	merged := append([]indexRow(nil), existing...)
+	for i, row := range merged {
+		positions[row.Path] = i
+	}
+	for _, row := range updated {
+		if i, ok := positions[row.Path]; ok {
+			merged[i] = row
+			continue
+		}
+		positions[row.Path] = len(merged)
+		merged = append(merged, row)
+	}
+	return merged
+}
@@ -0,0 +1,377 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// InspectObject décrit un groupe ou un dataset d'un fichier
+type InspectObject struct {
+	Path         string             `json:"path"`
+	Kind         string             `json:"kind"` // "group" ou "dataset"
+	Shape        []uint             `json:"shape,omitempty"`
+	Dtype        string             `json:"dtype,omitempty"`
+	Layout       string             `json:"layout,omitempty"`
+	Chunks       []uint             `json:"chunks,omitempty"`
+	Filters      []string           `json:"filters,omitempty"`
+	StoredBytes  uint64             `json:"stored_bytes,omitempty"`
+	LogicalBytes uint64             `json:"logical_bytes,omitempty"`
+	Attributes   []InspectAttribute `json:"attributes,omitempty"`
+	States       []InspectState     `json:"states,omitempty"`
+	Children     []*InspectObject   `json:"children,omitempty"`
+}
+
+// InspectAttribute est un attribut décodé
+type InspectAttribute struct {
+	Name  string      `json:"name"`
+	Dtype string      `json:"dtype"`
+	Value interface{} `json:"value"`
+}
+
+// InspectState est une entrée d'une table d'états (attributs a_state_<libellé> = code)
+type InspectState struct {
+	Code  int64  `json:"code"`
+	Label string `json:"label"`
+}
+
+// Préfixe des attributs des tables d'états ajoutées par addStateAttributes
+const stateAttributePrefix = "a_state_"
+
+// Conteneur HDF5 parcouru par inspect : *hdf5.File ou *hdf5.Group
+type inspectContainer interface {
+	attributeReader
+	NumObjects() (uint, error)
+	ObjectNameByIndex(idx uint) (string, error)
+	ObjectTypeByIndex(idx uint) (hdf5.GType, error)
+	OpenGroup(name string) (*hdf5.Group, error)
+	OpenDataset(name string) (*hdf5.Dataset, error)
+}
+
+// InspectFile décrit l'arborescence d'un fichier produit par le convertisseur : pour chaque
+// dataset, sa forme, son type, son stockage et ses attributs
+func InspectFile(f *hdf5.File) (*InspectObject, error) {
+	return inspectGroup(f, "/")
+}
+
+// Fonction pour décrire un groupe et, récursivement, son contenu
+func inspectGroup(g inspectContainer, path string) (*InspectObject, error) {
+	obj := &InspectObject{Path: path, Kind: "group"}
+	attrs, err := inspectAttributes(g)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	obj.Attributes = attrs
+
+	n, err := g.NumObjects()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint(0); i < n; i++ {
+		name, err := g.ObjectNameByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := g.ObjectTypeByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		childPath := strings.TrimSuffix(path, "/") + "/" + name
+
+		switch typ {
+		case hdf5.H5G_GROUP:
+			sub, err := g.OpenGroup(name)
+			if err != nil {
+				return nil, err
+			}
+			child, err := inspectGroup(sub, childPath)
+			sub.Close()
+			if err != nil {
+				return nil, err
+			}
+			obj.Children = append(obj.Children, child)
+
+		case hdf5.H5G_DATASET:
+			dset, err := g.OpenDataset(name)
+			if err != nil {
+				return nil, err
+			}
+			child, err := inspectDataset(dset, childPath)
+			dset.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", childPath, err)
+			}
+			obj.Children = append(obj.Children, child)
+		}
+	}
+	return obj, nil
+}
+
+// Fonction pour décrire un dataset : forme, type, stockage, filtres et attributs
+func inspectDataset(dset *hdf5.Dataset, path string) (*InspectObject, error) {
+	obj := &InspectObject{Path: path, Kind: "dataset"}
+
+	dtype, err := dset.Datatype()
+	if err != nil {
+		return nil, err
+	}
+	defer dtype.Close()
+	obj.Dtype = describeDatatype(dtype)
+
+	space := dset.Space()
+	if space == nil {
+		return nil, fmt.Errorf("espace de données inaccessible")
+	}
+	defer space.Close()
+	if space.SimpleExtentType() == hdf5.S_SIMPLE {
+		if obj.Shape, _, err = space.SimpleExtentDims(); err != nil {
+			return nil, err
+		}
+	}
+	obj.LogicalBytes = uint64(space.SimpleExtentNPoints()) * uint64(dtype.Size())
+	obj.StoredBytes = dset.StorageSize()
+
+	// Stockage, chunking et filtres
+	prop, err := dset.CreatePropList()
+	if err != nil {
+		return nil, err
+	}
+	defer prop.Close()
+	layout, err := prop.GetLayout()
+	if err != nil {
+		return nil, err
+	}
+	obj.Layout = layout.String()
+	if layout == hdf5.D_CHUNKED {
+		if obj.Chunks, err = prop.GetChunk(len(obj.Shape)); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < prop.NumFilters(); i++ {
+		filter, err := prop.Filter(i)
+		if err != nil {
+			return nil, err
+		}
+		obj.Filters = append(obj.Filters, describeFilter(filter))
+	}
+
+	if obj.Attributes, err = inspectAttributes(dset); err != nil {
+		return nil, err
+	}
+	obj.States = stateTable(obj.Attributes)
+	return obj, nil
+}
+
+// Fonction auxiliaire pour lire et décoder tous les attributs d'un objet
+func inspectAttributes(obj attributeReader) ([]InspectAttribute, error) {
+	names, err := attributeNames(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make([]InspectAttribute, 0, len(names))
+	for _, name := range names {
+		attr, err := obj.OpenAttribute(name)
+		if err != nil {
+			return nil, err
+		}
+		dtype, err := attr.Datatype()
+		attr.Close()
+		if err != nil {
+			return nil, err
+		}
+		desc := describeDatatype(dtype)
+		isCompound := dtype.Class() == hdf5.T_COMPOUND
+		dtype.Close()
+
+		var value interface{}
+		if isCompound {
+			value, err = readRecordsAttribute(obj, name)
+		} else {
+			value, err = readAttribute(obj, name)
+		}
+		if err != nil {
+			value = fmt.Sprintf("(non décodé: %v)", err)
+		}
+		attrs = append(attrs, InspectAttribute{Name: name, Dtype: desc, Value: jsonSafe(value)})
+	}
+	return attrs, nil
+}
+
+// Fonction auxiliaire pour lire un attribut composé (ex. "meta") en liste d'enregistrements
+func readRecordsAttribute(obj attributeReader, name string) ([]map[string]interface{}, error) {
+	layout, buf, n, err := readAttributeRecords(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]map[string]interface{}, n)
+	for i := range records {
+		records[i] = make(map[string]interface{}, len(layout.Fields))
+		for j, field := range layout.Fields {
+			records[i][field.Name] = layout.get(buf[i*layout.Size:], j)
+		}
+	}
+	return records, nil
+}
+
+// Fonction auxiliaire pour regrouper les attributs a_state_<libellé> en table d'états, par code
+func stateTable(attrs []InspectAttribute) []InspectState {
+	var states []InspectState
+	for _, attr := range attrs {
+		if !strings.HasPrefix(attr.Name, stateAttributePrefix) {
+			continue
+		}
+		if code, ok := attr.Value.(int64); ok {
+			states = append(states, InspectState{Code: code, Label: strings.TrimPrefix(attr.Name, stateAttributePrefix)})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Code < states[j].Code })
+	return states
+}
+
+// Fonction auxiliaire pour décrire un type HDF5 (ex. "float64", "string[12] UTF-8",
+// "enum int8 {FALSE=0, TRUE=1}", "compound {t: int64, value: float64}")
+func describeDatatype(dtype *hdf5.Datatype) string {
+	size := int(dtype.Size())
+	switch dtype.Class() {
+	case hdf5.T_INTEGER:
+		if dtype.IsSigned() {
+			return fmt.Sprintf("int%d", 8*size)
+		}
+		return fmt.Sprintf("uint%d", 8*size)
+
+	case hdf5.T_FLOAT:
+		return fmt.Sprintf("float%d", 8*size)
+
+	case hdf5.T_STRING:
+		cset := "ASCII"
+		if dtype.CharSet() == hdf5.T_CSET_UTF8 {
+			cset = "UTF-8"
+		}
+		if dtype.IsVariableStr() {
+			return "string " + cset
+		}
+		return fmt.Sprintf("string[%d] %s", size, cset)
+
+	case hdf5.T_ENUM:
+		base, err := dtype.Super()
+		if err != nil {
+			return "enum"
+		}
+		defer base.Close()
+		enum := &hdf5.EnumType{Datatype: *dtype}
+		members := make([]string, enum.NMembers())
+		for i := range members {
+			var value [8]byte
+			if err := enum.MemberValue(i, &value); err != nil {
+				return "enum " + describeDatatype(base)
+			}
+			members[i] = fmt.Sprintf("%s=%v", enum.MemberName(i), decodeInteger(value[:size], base.IsSigned()))
+		}
+		return fmt.Sprintf("enum %s {%s}", describeDatatype(base), strings.Join(members, ", "))
+
+	case hdf5.T_COMPOUND:
+		compound := &hdf5.CompoundType{Datatype: *dtype}
+		members := make([]string, compound.NMembers())
+		for i := range members {
+			ftype, err := compound.MemberType(i)
+			if err != nil {
+				return "compound"
+			}
+			members[i] = compound.MemberName(i) + ": " + describeDatatype(ftype)
+			ftype.Close()
+		}
+		return fmt.Sprintf("compound {%s}", strings.Join(members, ", "))
+
+	case hdf5.T_ARRAY:
+		base, err := dtype.Super()
+		if err != nil {
+			return "array"
+		}
+		defer base.Close()
+		array := &hdf5.ArrayType{Datatype: *dtype}
+		return fmt.Sprintf("%s%v", describeDatatype(base), array.ArrayDims())
+	}
+	return fmt.Sprintf("classe %d (%d octets)", dtype.Class(), size)
+}
+
+// Fonction auxiliaire pour décrire un filtre (ex. "deflate(9)")
+func describeFilter(filter hdf5.Filter) string {
+	name := filter.Name
+	switch filter.ID {
+	case 1:
+		name = "deflate"
+	case 2:
+		name = "shuffle"
+	case 3:
+		name = "fletcher32"
+	}
+	if name == "" {
+		name = fmt.Sprintf("filtre %d", filter.ID)
+	}
+	if len(filter.Params) == 0 {
+		return name
+	}
+	params := make([]string, len(filter.Params))
+	for i, p := range filter.Params {
+		params[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(params, ","))
+}
+
+// Fonction auxiliaire pour rendre une valeur encodable en JSON (NaN et infinis en texte)
+func jsonSafe(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonSafe(v[i])
+		}
+	}
+	return value
+}
+
+// OpenObjects compte les objets HDF5 encore ouverts dans un fichier, identifiant du
+// fichier compris (diagnostic des fuites de handles)
+type OpenObjects struct {
+	Files      int `json:"files"`
+	Datasets   int `json:"datasets"`
+	Groups     int `json:"groups"`
+	Datatypes  int `json:"datatypes"` // types nommés
+	Attributes int `json:"attributes"`
+}
+
+// Leaked retourne le nombre d'objets ouverts autres que le fichier lui-même
+func (o OpenObjects) Leaked() int {
+	return o.Datasets + o.Groups + o.Datatypes + o.Attributes
+}
+
+// CountOpenObjects compte les objets ouverts dans f (H5Fget_obj_count). Les types non
+// nommés, les espaces de données et les listes de propriétés, qui ne sont pas rattachés à
+// un fichier, ne sont pas comptés.
+func CountOpenObjects(f *hdf5.File) (OpenObjects, error) {
+	var counts OpenObjects
+	for _, kind := range []struct {
+		kind  hdf5.ObjectKind
+		count *int
+	}{
+		{hdf5.F_OBJ_FILE, &counts.Files},
+		{hdf5.F_OBJ_DATASET, &counts.Datasets},
+		{hdf5.F_OBJ_GROUP, &counts.Groups},
+		{hdf5.F_OBJ_DATATYPE, &counts.Datatypes},
+		{hdf5.F_OBJ_ATTR, &counts.Attributes},
+	} {
+		n, err := f.ObjectCount(kind.kind)
+		if err != nil {
+			return counts, err
+		}
+		*kind.count = n
+	}
+	return counts, nil
+}
@@ -0,0 +1,155 @@
+package converter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Écriture au format MAT-file v7.3 de MATLAB : un fichier HDF5 précédé d'un bloc utilisateur
+// de 512 octets contenant l'en-tête MATLAB, dont chaque dataset de la racine porte l'attribut
+// MATLAB_class et devient une variable au chargement (load('out.mat')).
+
+// Taille du bloc utilisateur réservé à l'en-tête MATLAB
+const matUserblockSize = 512
+
+// Longueur maximale d'un nom de variable MATLAB (namelengthmax)
+const matMaxNameLength = 63
+
+// Mots réservés de MATLAB, qui ne peuvent pas servir de nom de variable (iskeyword)
+var matKeywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "classdef": true, "continue": true,
+	"else": true, "elseif": true, "end": true, "for": true, "function": true,
+	"global": true, "if": true, "otherwise": true, "parfor": true, "persistent": true,
+	"return": true, "spmd": true, "switch": true, "try": true, "while": true,
+}
+
+// CreateMatFile crée un fichier HDF5 avec le bloc utilisateur de l'en-tête MATLAB (format mat).
+// L'en-tête lui-même est écrit par WriteMatHeader, une fois le fichier HDF5 fermé.
+func CreateMatFile(name string) (*hdf5.File, error) {
+	fcpl, err := hdf5.NewPropList(hdf5.P_FILE_CREATE)
+	if err != nil {
+		return nil, err
+	}
+	defer fcpl.Close()
+	if err := fcpl.SetUserblock(matUserblockSize); err != nil {
+		return nil, err
+	}
+	return hdf5.CreateFileWith(name, hdf5.F_ACC_TRUNC, fcpl)
+}
+
+// WriteMatHeader écrit l'en-tête MATLAB dans le bloc utilisateur du fichier name :
+// texte descriptif (116 octets), décalage des données système (8 octets),
+// version 0x0200 et indicateur d'ordre des octets "IM" (petit-boutiste)
+func WriteMatHeader(name string) error {
+	header := make([]byte, matUserblockSize)
+	text := fmt.Sprintf("MATLAB 7.3 MAT-file, Platform: GLNXA64, Created on: %s HDF5 schema 1.00 .",
+		time.Now().Format("Mon Jan _2 15:04:05 2006"))
+	copy(header, fmt.Sprintf("%-116s", text))
+	copy(header[116:124], strings.Repeat(" ", 8))
+	binary.LittleEndian.PutUint16(header[124:126], 0x0200)
+	copy(header[126:128], "IM")
+
+	file, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(header, 0); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Fonction auxiliaire pour transformer un nom de série en identifiant MATLAB valide :
+// lettres ASCII, chiffres et "_", commençant par une lettre, au plus 63 caractères
+// (comme matlab.lang.makeValidName, les noms invalides en tête sont préfixés par "x")
+func matlabIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	id := b.String()
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "x" + id
+	} else if matKeywords[id] {
+		id = "x" + strings.ToUpper(id[:1]) + id[1:]
+	}
+	return truncateMatlabName(id, "")
+}
+
+// Fonction auxiliaire pour ajouter un suffixe à un identifiant MATLAB en restant dans la limite de longueur
+func truncateMatlabName(id, suffix string) string {
+	if len(id)+len(suffix) > matMaxNameLength {
+		id = id[:matMaxNameLength-len(suffix)]
+	}
+	return id + suffix
+}
+
+// Fonction pour écrire une série en variable MATLAB de classe double. MATLAB range les
+// matrices par colonnes : la matrice rows×cols est écrite en dataset HDF5 cols×rows.
+func writeMatDataset(loc location, name string, entry DataEntryFloat, attrs attributeSize, opts Options) (*hdf5.Dataset, hdf5.Layout, error) {
+	rows := len(entry.V)
+	cols := len(entry.V[0])
+
+	space, err := hdf5.CreateSimpleDataspace([]uint{uint(cols), uint(rows)}, nil)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de l'espace de données: %w", err)
+	}
+	defer space.Close()
+
+	// Mêmes formes de chunks qu'en mode matrice, transposées
+	chunks := []uint{1, uint(rows)}
+	switch opts.Chunking {
+	case ChunkingRow:
+		chunks = []uint{uint(cols), 1}
+	case ChunkingMatrix:
+		chunks = []uint{uint(cols), uint(rows)}
+	}
+	prop, layout, err := newDatasetPropList(rows, cols, chunks, attrs.add("MATLAB_class", fixedString("double")), opts)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+	defer prop.Close()
+
+	dset, err := loc.CreateDatasetWith(name, hdf5.T_NATIVE_DOUBLE, space, prop)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du dataset '%s': %w", name, err)
+	}
+
+	// Données transposées, par blocs de lignes de la série (colonnes du dataset) : la
+	// colonne j d'un bloc est contiguë
+	block := alignBlockRows(blockRows(rows, opts), chunks[1:])
+	flatData := make([]float64, block*cols)
+	err = writeBlocks(rows, block, func(start, n int) error {
+		data := flatData[:n*cols]
+		clear(data)
+		for i, row := range entry.V[start : start+n] {
+			for j := 0; j < cols && j < len(row); j++ {
+				data[j*n+i] = row[j]
+			}
+		}
+		return writeHyperslab(dset, []uint{0, uint(start)}, []uint{uint(cols), uint(n)}, &data)
+	})
+	if err != nil {
+		dset.Close() // pile d'erreurs relevée par writeHyperslab
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	if err := writeAttribute(dset, "MATLAB_class", fixedString("double")); err != nil {
+		err = stageError(StageAttributes, "ajout de l'attribut 'MATLAB_class': %w", err)
+		dset.Close()
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	return dset, layout, nil
+}
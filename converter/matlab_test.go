@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+// Noms de séries transformés en identifiants MATLAB valides
+func TestMatlabIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "speed", want: "speed"},
+		{name: "engine.temp", want: "engine_temp"},
+		{name: "température", want: "temp_rature"},
+		{name: "2nd", want: "x2nd"},
+		{name: "_private", want: "x_private"},
+		{name: "", want: "x"},
+		{name: "end", want: "xEnd"},
+		{name: "function", want: "xFunction"},
+		{name: strings.Repeat("a", 70), want: strings.Repeat("a", matMaxNameLength)},
+		{name: "9" + strings.Repeat("a", 70), want: "x9" + strings.Repeat("a", matMaxNameLength-2)},
+	}
+	for _, tt := range tests {
+		if got := matlabIdentifier(tt.name); got != tt.want {
+			t.Errorf("matlabIdentifier(%q) = %q, attendu %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Suffixes ajoutés aux identifiants MATLAB sans dépasser la longueur maximale
+func TestTruncateMatlabName(t *testing.T) {
+	long := strings.Repeat("a", matMaxNameLength)
+	tests := []struct {
+		id, suffix, want string
+	}{
+		{id: "speed", suffix: "_2", want: "speed_2"},
+		{id: "speed", suffix: "", want: "speed"},
+		{id: long, suffix: "_2", want: long[:matMaxNameLength-2] + "_2"},
+		{id: long[:matMaxNameLength-1], suffix: "_2", want: long[:matMaxNameLength-2] + "_2"},
+		{id: long[:matMaxNameLength-2], suffix: "_2", want: long[:matMaxNameLength-2] + "_2"},
+	}
+	for _, tt := range tests {
+		got := truncateMatlabName(tt.id, tt.suffix)
+		if got != tt.want {
+			t.Errorf("truncateMatlabName(%q, %q) = %q, attendu %q", tt.id, tt.suffix, got, tt.want)
+		}
+		if len(got) > matMaxNameLength {
+			t.Errorf("truncateMatlabName(%q, %q): %d caractères, au plus %d", tt.id, tt.suffix, len(got), matMaxNameLength)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Séparateur utilisé pour aplatir les objets JSON imbriqués dans les noms d'attributs
+const metadataSeparator = "."
+
+// Attribut à écrire sur un objet HDF5
+type namedAttribute struct {
+	Name   string      // nom complet de l'attribut, préfixe compris (ex. "l_site")
+	Source string      // section JSON d'origine : "l", "a" ou "x"
+	Key    string      // clé d'origine, aplatie (ex. "site" ou "vehicle.axle")
+	Value  interface{} // valeur Go, de type naturel
+}
+
+// Fonction auxiliaire pour rassembler les métadonnées "l", "a" et "x" d'une entrée en
+// attributs l_*, a_* et x_*. Retourne aussi les noms des attributs en double, ignorés
+// (voir flattenMetadata).
+func entryMetadata(entry DataEntryFloat) ([]namedAttribute, []string) {
+	var metadata []namedAttribute
+	var duplicates []string
+	for _, section := range []struct {
+		prefix string
+		values map[string]interface{}
+	}{{"l_", entry.L}, {"a_", entry.A}, {"x_", entry.X}} {
+		attrs, dup := flattenMetadata(section.prefix, section.values)
+		metadata = append(metadata, attrs...)
+		duplicates = append(duplicates, dup...)
+	}
+	return metadata, duplicates
+}
+
+// Fonction auxiliaire pour convertir une map de métadonnées JSON ("l", "a") en attributs.
+// Les objets imbriqués sont aplatis avec metadataSeparator : {"a": {"b": 1}} donne
+// l'attribut <prefix>a.b. Une clé contenant le séparateur peut donner le même nom
+// ({"a.b": 1} et {"a": {"b": 2}}) : les clés étant parcourues dans l'ordre, seul le
+// premier attribut est gardé et le nom est retourné parmi les doublons. Les attributs
+// sont triés par nom.
+func flattenMetadata(prefix string, metadata map[string]interface{}) ([]namedAttribute, []string) {
+	var attrs []namedAttribute
+	var duplicates []string
+	seen := make(map[string]bool)
+	source := strings.TrimSuffix(prefix, "_")
+	for _, key := range sortedKeys(metadata) {
+		attrs, duplicates = appendMetadata(attrs, duplicates, seen, prefix, source, key, metadata[key])
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return attrs, duplicates
+}
+
+// Fonction auxiliaire pour ajouter une métadonnée à attrs, en aplatissant récursivement
+// les objets imbriqués ; les noms déjà présents dans seen sont ajoutés à duplicates
+func appendMetadata(attrs []namedAttribute, duplicates []string, seen map[string]bool, prefix, source, key string, value interface{}) ([]namedAttribute, []string) {
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		name := prefix + key
+		if seen[name] {
+			return attrs, append(duplicates, name)
+		}
+		seen[name] = true
+		return append(attrs, namedAttribute{Name: name, Source: source, Key: key, Value: metadataValue(value)}), duplicates
+	}
+	for _, subKey := range sortedKeys(object) {
+		attrs, duplicates = appendMetadata(attrs, duplicates, seen, prefix, source, key+metadataSeparator+subKey, object[subKey])
+	}
+	return attrs, duplicates
+}
+
+// Fonction auxiliaire pour lister les clés d'une map dans l'ordre alphabétique
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Préfixe des métadonnées conservées sous forme de texte JSON, qui les distingue des
+// chaînes à la relecture ; une chaîne commençant par ce préfixe est elle-même conservée
+// en texte JSON
+const jsonValuePrefix = "json:"
+
+// Fonction auxiliaire pour donner à une valeur JSON (ou ajoutée par le convertisseur)
+// son type Go naturel : entier (int64) ou flottant (float64), booléen, chaîne, ou slice de l'un de ces types
+// pour les tableaux homogènes. Les uint8 des tables d'états du convertisseur (state_*)
+// gardent leur largeur. Les autres valeurs (null, tableaux mixtes ou imbriqués,
+// objets vides, nombres hors plage) sont conservées sous forme de texte JSON précédé de
+// jsonValuePrefix.
+func metadataValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case int:
+		return int64(v)
+	case uint8:
+		return v
+	case float64:
+		return v
+	case bool:
+		return v
+	case string:
+		if !strings.HasPrefix(v, jsonValuePrefix) {
+			return v
+		}
+	case []interface{}:
+		if array := metadataArray(v); array != nil {
+			return array
+		}
+	}
+	return jsonValuePrefix + jsonText(value)
+}
+
+// Fonction auxiliaire pour relire une métadonnée : le texte JSON précédé de
+// jsonValuePrefix est décodé, les autres valeurs sont retournées telles quelles
+func decodeMetadataValue(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok || !strings.HasPrefix(text, jsonValuePrefix) {
+		return value
+	}
+	var decoded interface{}
+	if err := decodeJSON([]byte(text[len(jsonValuePrefix):]), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// Fonction auxiliaire pour convertir un tableau JSON homogène en slice typée.
+// Retourne nil si le tableau est vide ou si ses éléments sont de types différents.
+func metadataArray(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	scalars := make([]interface{}, len(values))
+	for i, value := range values {
+		scalars[i] = metadataValue(value)
+	}
+
+	switch scalars[0].(type) {
+	case bool:
+		array := make([]bool, len(scalars))
+		for i, scalar := range scalars {
+			b, ok := scalar.(bool)
+			if !ok {
+				return nil
+			}
+			array[i] = b
+		}
+		return array
+	case string:
+		array := make([]string, len(scalars))
+		for i, scalar := range scalars {
+			// Une chaîne conservée en texte JSON ne peut pas figurer dans un tableau de chaînes
+			s, ok := scalar.(string)
+			if original, isString := values[i].(string); !ok || !isString || s != original {
+				return nil
+			}
+			array[i] = s
+		}
+		return array
+	case int64, float64:
+		ints := make([]int64, len(scalars))
+		floats := make([]float64, len(scalars))
+		allInts := true
+		for i, scalar := range scalars {
+			switch n := scalar.(type) {
+			case int64:
+				ints[i] = n
+				floats[i] = float64(n)
+			case float64:
+				floats[i] = n
+				allInts = false
+			default:
+				return nil
+			}
+		}
+		if allInts {
+			return ints
+		}
+		return floats
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour sérialiser une valeur JSON quelconque en texte
+func jsonText(value interface{}) string {
+	text, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// Champs JSON connus d'une entrée ; les autres sont conservés dans DataEntryRaw.X
+var knownEntryFields = []string{"c", "l", "a", "la", "v"}
+
+// UnmarshalJSON décode une entrée en conservant ses champs inconnus dans X. L'entrée est
+// découpée une seule fois en champs, puis chaque champ est décodé à sa place : les
+// valeurs de V, volumineuses, ne sont analysées qu'une fois.
+func (e *DataEntryRaw) UnmarshalJSON(data []byte) error {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+
+	*e = DataEntryRaw{}
+	for key, raw := range all {
+		field, name := e.knownField(key)
+		if field == nil {
+			var value interface{}
+			if err := decodeJSON(raw, &value); err != nil {
+				return fmt.Errorf("champ '%s': %w", key, err)
+			}
+			if e.X == nil {
+				e.X = make(map[string]interface{})
+			}
+			e.X[key] = value
+			continue
+		}
+		// Comme encoding/json, les champs sont associés sans tenir compte de la casse ;
+		// le nom exact l'emporte sur ses variantes
+		if key != name {
+			if _, exact := all[name]; exact {
+				continue
+			}
+		}
+		if err := decodeJSON(raw, field); err != nil {
+			return fmt.Errorf("champ '%s': %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour trouver le champ connu correspondant à une clé JSON : retourne
+// l'adresse du champ et son nom exact, ou nil pour un champ inconnu
+func (e *DataEntryRaw) knownField(key string) (interface{}, string) {
+	for _, name := range knownEntryFields {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		switch name {
+		case "c":
+			return &e.C, name
+		case "l":
+			return &e.L, name
+		case "a":
+			return &e.A, name
+		case "la":
+			return &e.La, name
+		case "v":
+			return &e.V, name
+		}
+	}
+	return nil, ""
+}
+
+// MarshalJSON encode une entrée en replaçant ses champs inconnus (X) au premier niveau
+func (e DataEntryRaw) MarshalJSON() ([]byte, error) {
+	// Type sans méthode MarshalJSON, pour éviter la récursion
+	type entryFields DataEntryRaw
+
+	data, err := json.Marshal(entryFields(e))
+	if err != nil || len(e.X) == 0 {
+		return data, err
+	}
+	extra, err := json.Marshal(e.X)
+	if err != nil {
+		return nil, err
+	}
+	// {"c":...,"v":...} + {"x1":...} -> {"c":...,"v":...,"x1":...}
+	return append(append(data[:len(data)-1], ','), extra[1:]...), nil
+}
+
+// Fonction auxiliaire pour décoder du JSON en conservant les nombres en json.Number
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// Fonction auxiliaire pour vérifier qu'une entrée ne contient pas de champ inconnu
+func checkEntryFields(rawEntry DataEntryRaw, datasetIndex, entryIndex int) error {
+	if len(rawEntry.X) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(rawEntry.X))
+	for key := range rawEntry.X {
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+	return fmt.Errorf("champs inconnus %q dans l'entrée [%d][%d] ('%s')",
+		fields, datasetIndex, entryIndex, rawEntry.C)
+}
+
+// Nom de l'attribut composé regroupant toutes les métadonnées d'un dataset
+const metadataRecordName = "meta"
+
+// Nombre d'attributs au-delà duquel HDF5 passe par défaut en stockage dense
+// (valeur par défaut de H5Pset_attr_phase_change)
+const maxCompactAttributes = 8
+
+// Fonction auxiliaire pour écrire les métadonnées dans un seul attribut : un tableau 1-D
+// d'enregistrements composés (source, key, value), chaînes UTF-8 de longueur fixe.
+// Les valeurs autres que des chaînes sont stockées en texte JSON.
+func writeMetadataRecord(obj attributeHolder, name string, attrs []namedAttribute) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	layout, values := metadataRecordLayout(attrs)
+	dtype, err := layout.datatype()
+	if err != nil {
+		return err
+	}
+	defer dtype.Close()
+
+	// Remplir les enregistrements
+	buf := make([]byte, layout.Size*len(attrs))
+	for i, attr := range attrs {
+		record := buf[i*layout.Size:]
+		layout.set(record, 0, attr.Source)
+		layout.set(record, 1, attr.Key)
+		layout.set(record, 2, values[i])
+	}
+
+	dspace, err := hdf5.CreateSimpleDataspace([]uint{uint(len(attrs))}, nil)
+	if err != nil {
+		return err
+	}
+	defer dspace.Close()
+
+	attr, err := obj.CreateAttribute(name, &dtype.Datatype, dspace)
+	if err != nil {
+		return hdf5.CaptureErrorStack(err)
+	}
+	defer attr.Close()
+
+	return hdf5.CaptureErrorStack(attr.Write(&buf[0], &dtype.Datatype))
+}
+
+// Fonction auxiliaire pour décrire les enregistrements de l'attribut "meta" : la taille de
+// chaque champ est celle de sa plus longue chaîne. Retourne aussi les valeurs en texte.
+func metadataRecordLayout(attrs []namedAttribute) (*recordLayout, []string) {
+	values := make([]string, len(attrs))
+	var sourceLen, keyLen, valueLen int
+	for i, attr := range attrs {
+		values[i] = metadataText(attr.Value)
+		sourceLen = max(sourceLen, len(attr.Source))
+		keyLen = max(keyLen, len(attr.Key))
+		valueLen = max(valueLen, len(values[i]))
+	}
+	return newRecordLayout(stringField("source", sourceLen), stringField("key", keyLen), stringField("value", valueLen)), values
+}
+
+// Fonction auxiliaire pour représenter une valeur de métadonnée en texte. Les valeurs
+// autres que les chaînes sont écrites en texte JSON ; une chaîne qui serait relue comme du
+// texte JSON valide est précédée de jsonValuePrefix.
+func metadataText(value interface{}) string {
+	text, ok := value.(string)
+	if !ok {
+		return jsonText(value)
+	}
+	var decoded interface{}
+	if strings.HasPrefix(text, jsonValuePrefix) || decodeJSON([]byte(text), &decoded) != nil {
+		return text
+	}
+	return jsonValuePrefix + jsonText(text)
+}
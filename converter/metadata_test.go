@@ -0,0 +1,206 @@
+package converter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Aplatissement des métadonnées imbriquées : attributs triés par nom, premier attribut
+// gardé en cas de doublon
+func TestFlattenMetadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		metadata   string
+		want       []namedAttribute
+		duplicates []string
+	}{
+		{
+			name:     "valeurs scalaires",
+			metadata: `{"site": "nord", "n": 3, "k": 1.5, "ok": true}`,
+			want: []namedAttribute{
+				{Name: "l_k", Source: "l", Key: "k", Value: 1.5},
+				{Name: "l_n", Source: "l", Key: "n", Value: int64(3)},
+				{Name: "l_ok", Source: "l", Key: "ok", Value: true},
+				{Name: "l_site", Source: "l", Key: "site", Value: "nord"},
+			},
+		},
+		{
+			name:     "objets imbriqués",
+			metadata: `{"vehicle": {"axle": {"count": 2}, "type": "bus"}, "empty": {}}`,
+			want: []namedAttribute{
+				{Name: "l_empty", Source: "l", Key: "empty", Value: "json:{}"},
+				{Name: "l_vehicle.axle.count", Source: "l", Key: "vehicle.axle.count", Value: int64(2)},
+				{Name: "l_vehicle.type", Source: "l", Key: "vehicle.type", Value: "bus"},
+			},
+		},
+		{
+			name:     "clé contenant le séparateur",
+			metadata: `{"a": {"b": 2}, "a.b": 1}`,
+			want: []namedAttribute{
+				{Name: "l_a.b", Source: "l", Key: "a.b", Value: int64(2)},
+			},
+			duplicates: []string{"l_a.b"},
+		},
+		{
+			name:     "tableaux",
+			metadata: `{"ints": [1, 2], "floats": [1, 2.5], "mixed": [1, "x"], "nested": [[1]]}`,
+			want: []namedAttribute{
+				{Name: "l_floats", Source: "l", Key: "floats", Value: []float64{1, 2.5}},
+				{Name: "l_ints", Source: "l", Key: "ints", Value: []int64{1, 2}},
+				{Name: "l_mixed", Source: "l", Key: "mixed", Value: `json:[1,"x"]`},
+				{Name: "l_nested", Source: "l", Key: "nested", Value: "json:[[1]]"},
+			},
+		},
+		{
+			name:     "valeurs conservées en texte JSON",
+			metadata: `{"none": null, "prefixed": "json:1", "names": ["a", "json:b"], "empty": []}`,
+			want: []namedAttribute{
+				{Name: "l_empty", Source: "l", Key: "empty", Value: "json:[]"},
+				{Name: "l_names", Source: "l", Key: "names", Value: `json:["a","json:b"]`},
+				{Name: "l_none", Source: "l", Key: "none", Value: "json:null"},
+				{Name: "l_prefixed", Source: "l", Key: "prefixed", Value: `json:"json:1"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metadata map[string]interface{}
+			if err := decodeJSON([]byte(tt.metadata), &metadata); err != nil {
+				t.Fatalf("décodage: %v", err)
+			}
+			attrs, duplicates := flattenMetadata("l_", metadata)
+			if !reflect.DeepEqual(attrs, tt.want) {
+				t.Errorf("attributs %+v, attendu %+v", attrs, tt.want)
+			}
+			if !reflect.DeepEqual(duplicates, tt.duplicates) {
+				t.Errorf("doublons %q, attendu %q", duplicates, tt.duplicates)
+			}
+		})
+	}
+}
+
+// Les métadonnées relues des attributs ou de l'attribut composé "meta" sont les valeurs
+// JSON d'origine, y compris celles conservées en texte JSON et les chaînes qui
+// ressemblent à du texte JSON
+func TestMetadataRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string // valeur JSON d'origine
+	}{
+		{name: "chaîne", value: `"nord"`},
+		{name: "chaîne numérique", value: `"123"`},
+		{name: "chaîne préfixée", value: `"json:[1]"`},
+		{name: "entier", value: `3`},
+		{name: "flottant", value: `1.5`},
+		{name: "booléen", value: `true`},
+		{name: "null", value: `null`},
+		{name: "tableau d'entiers", value: `[1,2]`},
+		{name: "tableau de chaînes", value: `["a","b"]`},
+		{name: "tableau mixte", value: `[1,"x"]`},
+		{name: "tableau imbriqué", value: `[[1],[2,3]]`},
+		{name: "tableau vide", value: `[]`},
+		{name: "objet vide", value: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original interface{}
+			if err := decodeJSON([]byte(tt.value), &original); err != nil {
+				t.Fatalf("décodage: %v", err)
+			}
+			value := metadataValue(original)
+			// Valeur relue par readAttribute : les slices typées deviennent des []interface{}
+			if array := reflect.ValueOf(value); array.Kind() == reflect.Slice {
+				values := make([]interface{}, array.Len())
+				for i := range values {
+					values[i] = array.Index(i).Interface()
+				}
+				value = values
+			}
+
+			for source, got := range map[string]interface{}{
+				"attribut": decodeMetadataValue(value),
+				"meta":     metadataFromText(metadataText(metadataValue(original))),
+			} {
+				if text := jsonText(got); text != tt.value {
+					t.Errorf("%s: valeur relue %s, attendu %s", source, text, tt.value)
+				}
+			}
+		})
+	}
+}
+
+// Décodage d'une entrée : champs connus sans tenir compte de la casse (le nom exact
+// l'emporte), champs inconnus conservés dans X
+func TestDataEntryRawUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  DataEntryRaw
+	}{
+		{
+			name:  "champs connus",
+			input: `{"c": "a", "l": {"site": "nord"}, "la": 2, "v": [[1000, 1]]}`,
+			want: DataEntryRaw{
+				C:  "a",
+				L:  map[string]interface{}{"site": "nord"},
+				La: 2,
+				V:  [][]interface{}{{json.Number("1000"), json.Number("1")}},
+			},
+		},
+		{
+			name:  "casse différente",
+			input: `{"C": "a", "LA": 1, "V": [[1000]]}`,
+			want:  DataEntryRaw{C: "a", La: 1, V: [][]interface{}{{json.Number("1000")}}},
+		},
+		{
+			name:  "nom exact prioritaire",
+			input: `{"C": "majuscule", "c": "exact"}`,
+			want:  DataEntryRaw{C: "exact"},
+		},
+		{
+			name:  "champs inconnus",
+			input: `{"c": "a", "unit": "°C", "gain": 2, "cal": {"k": [1, 2]}}`,
+			want: DataEntryRaw{
+				C: "a",
+				X: map[string]interface{}{
+					"unit": "°C",
+					"gain": json.Number("2"),
+					"cal":  map[string]interface{}{"k": []interface{}{json.Number("1"), json.Number("2")}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry DataEntryRaw
+			if err := json.Unmarshal([]byte(tt.input), &entry); err != nil {
+				t.Fatalf("décodage: %v", err)
+			}
+			if !reflect.DeepEqual(entry, tt.want) {
+				t.Errorf("entrée %+v, attendu %+v", entry, tt.want)
+			}
+
+			// Les champs inconnus sont réécrits au premier niveau
+			data, err := json.Marshal(entry)
+			if err != nil {
+				t.Fatalf("encodage: %v", err)
+			}
+			var again DataEntryRaw
+			if err := decodeJSON(data, &again); err != nil {
+				t.Fatalf("décodage de %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(again.X, entry.X) {
+				t.Errorf("champs inconnus après aller-retour %+v, attendu %+v", again.X, entry.X)
+			}
+		})
+	}
+
+	// Un champ connu mal typé est une erreur qui nomme le champ
+	var entry DataEntryRaw
+	err := json.Unmarshal([]byte(`{"c": "a", "la": 300}`), &entry)
+	if err == nil || !strings.Contains(err.Error(), "champ 'la'") {
+		t.Errorf("la hors plage: erreur %v, attendu une erreur du champ 'la'", err)
+	}
+}
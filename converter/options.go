@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Formats de sortie des séries
+const (
+	FormatMatrix   = "matrix"   // matrice 2-D rows×cols de float64, colonne 0 = horodatage
+	FormatCompound = "compound" // tableau 1-D d'enregistrements composés (t, value...)
+	FormatCF       = "cf"       // variables 1-D et coordonnée temps, conventions CF (NetCDF-4)
+	FormatPandas   = "pandas"   // groupe par série au format table de PyTables, lisible par pandas.read_hdf
+	FormatMat      = "mat"      // MAT-file v7.3 de MATLAB, une variable par série
+)
+
+// Formes de chunks des matrices
+const (
+	ChunkingColumn = "column"
+	ChunkingRow    = "row"
+	ChunkingMatrix = "matrix"
+)
+
+// Options de la conversion : prétraitement des séries et écriture des datasets
+type Options struct {
+	Format               string // FormatMatrix, FormatCompound, FormatCF, FormatPandas ou FormatMat
+	MetadataInAttributes bool   // métadonnées en attributs l_*, a_*, x_*
+	MetadataInRecord     bool   // métadonnées dans l'attribut composé "meta"
+	StrictFields         bool   // rejeter les entrées contenant des champs JSON inconnus
+	StrictValues         bool   // échouer sur la première valeur de V non convertie
+	Compression          int    // niveau GZIP des datasets chunkés (0 : aucune compression)
+	Shuffle              bool   // filtre shuffle avant la compression des datasets chunkés
+	DirectChunks         bool   // matrices chunkées compressées en parallèle et écrites chunk par chunk
+	BlockRows            int    // lignes par bloc d'écriture des séries (0 : d'un seul tenant)
+	Chunking             string // forme des chunks des matrices : ChunkingColumn, ChunkingRow ou ChunkingMatrix
+	CompactMaxBytes      int    // seuils de choix du stockage, voir chooseLayout
+	ContiguousMaxBytes   int
+	ReverseRows          bool    // inverser l'ordre des lignes (JSON le plus récent en premier)
+	TimestampDivisor     float64 // diviseur appliqué aux horodatages (colonne 0) lors du prétraitement
+	Append               bool    // prolonger les séries existantes ; datasets chunkés et extensibles
+	KeepGoing            bool    // ignorer les séries en échec au lieu d'interrompre la conversion
+	Workers              int     // goroutines de décodage et de prétraitement (0 : une par processeur)
+
+	// Progress, si non nil, est appelée après chaque entrée depuis le goroutine appelant
+	// de Convert ; elle doit rendre la main rapidement
+	Progress func(Progress)
+
+	// Logger reçoit les avertissements et, au niveau debug, le détail des séries écrites
+	// (nil : slog.Default()) ; chaque message porte un attribut "event" stable
+	Logger *slog.Logger
+}
+
+// DefaultOptions retourne les options qui reproduisent le comportement historique du convertisseur
+func DefaultOptions() Options {
+	return Options{
+		Format:               FormatMatrix,
+		MetadataInAttributes: true,
+		Compression:          9,
+		Chunking:             ChunkingColumn,
+		CompactMaxBytes:      compactLayoutMaxBytes,
+		ContiguousMaxBytes:   contiguousLayoutMaxBytes,
+		ReverseRows:          true,
+		TimestampDivisor:     1000,
+	}
+}
+
+// Validate vérifie la cohérence des options
+func (opts Options) Validate() error {
+	switch opts.Format {
+	case FormatMatrix, FormatCompound, FormatCF, FormatPandas, FormatMat:
+	default:
+		return fmt.Errorf("format de sortie inconnu: '%s'", opts.Format)
+	}
+	if !opts.MetadataInAttributes && !opts.MetadataInRecord {
+		return fmt.Errorf("les métadonnées doivent être écrites en attributs, en enregistrement ou les deux")
+	}
+	switch opts.Chunking {
+	case ChunkingColumn, ChunkingRow, ChunkingMatrix:
+	default:
+		return fmt.Errorf("forme de chunks inconnue: '%s'", opts.Chunking)
+	}
+	if opts.Compression < 0 || opts.Compression > 9 {
+		return fmt.Errorf("niveau de compression invalide: %d (attendu entre 0 et 9)", opts.Compression)
+	}
+	if opts.CompactMaxBytes < 0 || opts.ContiguousMaxBytes < opts.CompactMaxBytes {
+		return fmt.Errorf("seuils de stockage invalides: compact %d, contigu %d", opts.CompactMaxBytes, opts.ContiguousMaxBytes)
+	}
+	if opts.TimestampDivisor <= 0 {
+		return fmt.Errorf("diviseur des horodatages invalide: %v", opts.TimestampDivisor)
+	}
+	if opts.BlockRows < 0 {
+		return fmt.Errorf("taille de bloc invalide: %d lignes", opts.BlockRows)
+	}
+	if opts.Workers < 0 {
+		return fmt.Errorf("nombre de workers invalide: %d", opts.Workers)
+	}
+	if opts.Append && opts.Format != FormatMatrix && opts.Format != FormatCompound {
+		return fmt.Errorf("le mode ajout n'est pris en charge que par les formats matrix et compound, pas '%s'", opts.Format)
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour obtenir le journal de la conversion
+func (opts Options) logger() *slog.Logger {
+	if opts.Logger != nil {
+		return opts.Logger
+	}
+	return slog.Default()
+}
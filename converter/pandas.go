@@ -0,0 +1,216 @@
+package converter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Écriture au format "table" de PyTables, tel que produit par pandas.DataFrame.to_hdf(format="table"),
+// pour que pandas.read_hdf(path, key) relise chaque série en DataFrame indexé par un DatetimeIndex.
+// Chaque série devient un groupe "<name>" contenant un dataset "table" d'enregistrements
+// (index int64 en nanosecondes, values_block_0 float64[n]) ; les attributs décrivant le
+// DataFrame sont ceux qu'écrit pandas, les valeurs Python étant sérialisées avec pickle.
+
+// Versions déclarées dans les attributs, celles des fichiers de référence de pandas et PyTables
+const (
+	pandasVersion      = "0.15.2"
+	pytablesFormat     = "2.1"
+	pytablesTable      = "2.7"
+	pytablesGroup      = "1.0"
+	pandasTableDataset = "table"
+	pandasIndexColumn  = "index"
+	pandasValuesBlock  = "values_block_0"
+)
+
+// WritePandasGlobalAttributes écrit les attributs PyTables du groupe racine (format pandas),
+// à appeler une fois par fichier avant Convert
+func WritePandasGlobalAttributes(f *hdf5.File) error {
+	for _, attr := range []namedAttribute{
+		{Name: "PYTABLES_FORMAT_VERSION", Value: fixedString(pytablesFormat)},
+		{Name: "CLASS", Value: fixedString("GROUP")},
+		{Name: "TITLE", Value: fixedString("")},
+		{Name: "VERSION", Value: fixedString(pytablesGroup)},
+	} {
+		if err := writeAttribute(f, attr.Name, attr.Value); err != nil {
+			return fmt.Errorf("attribut '%s': %w", attr.Name, err)
+		}
+	}
+	return nil
+}
+
+// Fonction pour écrire une série au format table de pandas, dans le groupe name.
+// Retourne le dataset "table", qui porte ensuite les métadonnées de l'entrée.
+func writePandasTable(loc location, name string, entry DataEntryFloat, opts Options) (*hdf5.Dataset, hdf5.Layout, error) {
+	rows := len(entry.V)
+	cols := len(entry.V[0])
+	if cols < 2 {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "pas de colonne de valeurs, impossible d'écrire la série au format pandas")
+	}
+
+	// Noms des colonnes de valeurs, comme en mode compound
+	record := compoundRecordLayout(cols)
+	columns := make([]string, 0, cols-1)
+	for _, field := range record.Fields[1:] {
+		columns = append(columns, field.Name)
+	}
+
+	group, err := loc.CreateGroup(name)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du groupe '%s': %w", name, err)
+	}
+	defer group.Close()
+	if err := writePandasGroupAttributes(group, columns); err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageAttributes, "ajout des attributs pandas du groupe '%s': %w", name, err)
+	}
+
+	// Type des lignes : index (int64) puis le bloc des valeurs (tableau de float64)
+	rowSize := 8 * cols
+	dtype, err := hdf5.NewCompoundType(rowSize)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du type composé: %w", err)
+	}
+	defer dtype.Close()
+	if err := dtype.Insert(pandasIndexColumn, 0, hdf5.T_NATIVE_INT64); err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du type composé: %w", err)
+	}
+	block, err := hdf5.NewArrayType(hdf5.T_NATIVE_DOUBLE, []int{cols - 1})
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du type tableau: %w", err)
+	}
+	defer block.Close()
+	if err := dtype.Insert(pandasValuesBlock, 8, &block.Datatype); err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du type composé: %w", err)
+	}
+
+	space, err := hdf5.CreateSimpleDataspace([]uint{uint(rows)}, nil)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de l'espace de données: %w", err)
+	}
+	defer space.Close()
+
+	// PyTables attend des tables chunkées, quelle que soit leur taille
+	prop, err := hdf5.NewPropList(hdf5.P_DATASET_CREATE)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de la liste de propriétés: %w", err)
+	}
+	defer prop.Close()
+	chunks := []uint{uint(rows)}
+	if err := prop.SetChunk(chunks); err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "configuration du chunking: %w", err)
+	}
+	setCompression(prop, opts)
+
+	// Avec les attributs PyTables, la limite de stockage compact est toujours dépassée
+	if err := prop.SetAttrPhaseChange(0, 0); err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "activation du stockage dense des attributs: %w", err)
+	}
+
+	dset, err := group.CreateDatasetWith(pandasTableDataset, &dtype.Datatype, space, prop)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du dataset '%s/%s': %w", name, pandasTableDataset, err)
+	}
+
+	// Encoder puis écrire les lignes, bloc par bloc ; l'index est l'horodatage d'origine
+	// (ms) converti en ns
+	blockSize := alignBlockRows(blockRows(rows, opts), chunks)
+	buf := make([]byte, blockSize*rowSize)
+	err = writeBlocks(rows, blockSize, func(start, n int) error {
+		data := buf[:n*rowSize]
+		for i, row := range entry.V[start : start+n] {
+			record := data[i*rowSize:]
+			t := int64(math.Round(row[0]*opts.TimestampDivisor)) * int64(1e6)
+			binary.NativeEndian.PutUint64(record, uint64(t))
+			for j := 1; j < cols; j++ {
+				value := math.NaN()
+				if j < len(row) {
+					value = row[j]
+				}
+				binary.NativeEndian.PutUint64(record[8*j:], math.Float64bits(value))
+			}
+		}
+		return writeRowsSubset(dset, uint(start), uint(n), 0, &data)
+	})
+	if err != nil {
+		dset.Close() // pile d'erreurs relevée par writeRowsSubset
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	if err := writePandasTableAttributes(dset, rows, columns); err != nil {
+		dset.Close() // pile d'erreurs relevée par writeAttribute
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageAttributes, "ajout des attributs PyTables de '%s/%s': %w", name, pandasTableDataset, err)
+	}
+
+	return dset, hdf5.D_CHUNKED, nil
+}
+
+// Fonction auxiliaire pour écrire les attributs pandas d'un groupe (DataFrame "frame_table")
+func writePandasGroupAttributes(group *hdf5.Group, columns []string) error {
+	// Valeurs Python, sérialisées avec pickle
+	pickled := []pickleItem{
+		{"index_cols", []interface{}{pickleTuple{0, pandasIndexColumn}}},
+		{"values_cols", []string{pandasValuesBlock}},
+		{"non_index_axes", []interface{}{pickleTuple{1, columns}}},
+		{"data_columns", []interface{}{}},
+		{"metadata", []interface{}{}},
+		{"info", pickleDict{
+			{1, pickleDict{{"names", []interface{}{nil}}, {"type", "Index"}}},
+			{pandasIndexColumn, pickleDict{{"freq", nil}, {"tz", nil}}},
+		}},
+	}
+
+	attrs := []namedAttribute{
+		{Name: "CLASS", Value: fixedString("GROUP")},
+		{Name: "TITLE", Value: fixedString("")},
+		{Name: "VERSION", Value: fixedString(pytablesGroup)},
+		{Name: "pandas_type", Value: fixedString("frame_table")},
+		{Name: "pandas_version", Value: fixedString(pandasVersion)},
+		{Name: "table_type", Value: fixedString("appendable_frame")},
+		{Name: "encoding", Value: fixedString("UTF-8")},
+		{Name: "errors", Value: fixedString("strict")},
+		{Name: "nan_rep", Value: fixedString("nan")},
+		{Name: "levels", Value: int64(1)},
+	}
+	for _, item := range pickled {
+		text, err := pickleProtocol0(item.Value)
+		if err != nil {
+			return err
+		}
+		attrs = append(attrs, namedAttribute{Name: item.Key.(string), Value: fixedString(text)})
+	}
+
+	for _, attr := range attrs {
+		if err := writeAttribute(group, attr.Name, attr.Value); err != nil {
+			return fmt.Errorf("attribut '%s': %w", attr.Name, err)
+		}
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour écrire les attributs PyTables et pandas du dataset "table"
+func writePandasTableAttributes(dset *hdf5.Dataset, rows int, columns []string) error {
+	kind, err := pickleProtocol0(columns)
+	if err != nil {
+		return err
+	}
+
+	attrs := []namedAttribute{
+		{Name: "CLASS", Value: fixedString("TABLE")},
+		{Name: "TITLE", Value: fixedString("")},
+		{Name: "VERSION", Value: fixedString(pytablesTable)},
+		{Name: "NROWS", Value: int64(rows)},
+		{Name: "FIELD_0_NAME", Value: fixedString(pandasIndexColumn)},
+		{Name: "FIELD_1_NAME", Value: fixedString(pandasValuesBlock)},
+		{Name: pandasIndexColumn + "_kind", Value: fixedString("datetime64")},
+		{Name: pandasValuesBlock + "_kind", Value: fixedString(kind)},
+		{Name: pandasValuesBlock + "_dtype", Value: fixedString("float64")},
+	}
+	for _, attr := range attrs {
+		if err := writeAttribute(dset, attr.Name, attr.Value); err != nil {
+			return fmt.Errorf("attribut '%s': %w", attr.Name, err)
+		}
+	}
+	return nil
+}
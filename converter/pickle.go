@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Encodage minimal au format pickle (protocole 0, texte ASCII) des quelques valeurs Python
+// que pandas stocke dans les attributs PyTables : None, entiers, chaînes, listes,
+// tuples et dictionnaires. PyTables désérialise les attributs chaîne se terminant par ".".
+
+// Tuple Python
+type pickleTuple []interface{}
+
+// Paire clé/valeur d'un dictionnaire Python (l'ordre d'insertion est conservé)
+type pickleItem struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Dictionnaire Python
+type pickleDict []pickleItem
+
+// Fonction pour sérialiser une valeur au format pickle protocole 0
+func pickleProtocol0(value interface{}) (string, error) {
+	var b strings.Builder
+	if err := appendPickle(&b, value); err != nil {
+		return "", err
+	}
+	b.WriteByte('.') // STOP
+	return b.String(), nil
+}
+
+func appendPickle(b *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		b.WriteString("N")
+	case int:
+		b.WriteString("I" + strconv.Itoa(v) + "\n")
+	case string:
+		b.WriteString("V" + pickleUnicode(v) + "\n")
+	case []string:
+		b.WriteString("(")
+		for _, item := range v {
+			b.WriteString("V" + pickleUnicode(item) + "\n")
+		}
+		b.WriteString("l")
+	case []interface{}:
+		b.WriteString("(")
+		for _, item := range v {
+			if err := appendPickle(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteString("l")
+	case pickleTuple:
+		b.WriteString("(")
+		for _, item := range v {
+			if err := appendPickle(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteString("t")
+	case pickleDict:
+		b.WriteString("(")
+		for _, item := range v {
+			if err := appendPickle(b, item.Key); err != nil {
+				return err
+			}
+			if err := appendPickle(b, item.Value); err != nil {
+				return err
+			}
+		}
+		b.WriteString("d")
+	default:
+		return fmt.Errorf("type non pris en charge par pickle: %T", value)
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour encoder une chaîne comme l'opcode UNICODE ("raw-unicode-escape") :
+// la barre oblique inverse, les caractères de contrôle et les caractères non ASCII sont
+// échappés en \uXXXX ou \UXXXXXXXX, pour que le résultat reste en ASCII sur une seule ligne
+func pickleUnicode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r > 0xffff:
+			fmt.Fprintf(&b, "\\U%08x", r)
+		case r < 0x20 || r == '\\' || r > 0x7e:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package converter
+
+import "testing"
+
+// Sérialisation pickle protocole 0 des valeurs stockées par pandas dans les attributs PyTables
+func TestPickleProtocol0(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "None", value: nil, want: "N."},
+		{name: "entier", value: 1, want: "I1\n."},
+		{name: "chaîne", value: "index", want: "Vindex\n."},
+		{name: "chaîne non ASCII", value: "é\\\n😀", want: "V\\u00e9\\u005c\\u000a\\U0001f600\n."},
+		{name: "liste de chaînes", value: []string{"a", "b"}, want: "(Va\nVb\nl."},
+		{name: "liste vide", value: []interface{}{}, want: "(l."},
+		{name: "tuple", value: pickleTuple{0, "index"}, want: "(I0\nVindex\nt."},
+		{
+			name:  "dictionnaire",
+			value: pickleDict{{"freq", nil}, {"tz", nil}},
+			want:  "(Vfreq\nNVtz\nNd.",
+		},
+		{
+			name:  "imbrication",
+			value: []interface{}{pickleTuple{1, []string{"x"}}},
+			want:  "((I1\n(Vx\nltl.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickleProtocol0(tt.value)
+			if err != nil {
+				t.Fatalf("pickleProtocol0(%#v): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("pickleProtocol0(%#v) = %q, attendu %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := pickleProtocol0(1.5); err == nil {
+		t.Errorf("type non pris en charge sérialisé sans erreur")
+	}
+}
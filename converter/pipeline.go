@@ -0,0 +1,226 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Pipeline de conversion : un goroutine lit le JSON source entrée par entrée, un groupe
+// de workers décode et prétraite les entrées en parallèle, et le goroutine appelant, seul
+// à utiliser la bibliothèque HDF5, reçoit les entrées dans l'ordre du fichier source.
+
+// Nombre d'entrées en cours (lues mais pas encore traitées par l'appelant) par worker :
+// borne la mémoire du pipeline, tampon de remise en ordre compris
+const entriesPerWorker = 4
+
+// Entrée du JSON source, numérotée dans l'ordre de lecture
+type pipelineJob struct {
+	seq        int
+	outerIndex int
+	entryIndex int
+	end        int64 // position dans le JSON source de la fin de l'entrée
+	data       json.RawMessage
+}
+
+// Entrée prétraitée, ou erreur de décodage ou de prétraitement de l'entrée
+type pipelineResult struct {
+	seq        int
+	outerIndex int
+	entryIndex int
+	end        int64 // position dans le JSON source de la fin de l'entrée
+	entry      DataEntryFloat
+	err        error
+}
+
+// Lecteur du JSON source comptant les octets lus, pour le suivi de l'avancement (lu par le
+// goroutine de lecture, consulté par le goroutine appelant)
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Fonction auxiliaire pour déterminer le nombre de workers (opts.Workers, ou un par
+// processeur disponible)
+func workerCount(opts Options) int {
+	if opts.Workers > 0 {
+		return opts.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Fonction pour faire passer le JSON source lu depuis r dans le pipeline : handle est
+// appelé depuis le goroutine appelant pour chaque entrée, dans l'ordre du fichier. Une
+// erreur de handle interrompt le pipeline et est retournée ; une erreur de lecture du
+// JSON est retournée une fois traitées les entrées lues avant elle.
+func runPipeline(ctx context.Context, r io.Reader, opts Options, handle func(pipelineResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := workerCount(opts)
+	inFlight := make(chan struct{}, workers*entriesPerWorker)
+	jobs := make(chan pipelineJob, workers)
+	results := make(chan pipelineResult, workers)
+
+	// Lecture du JSON
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		readErr <- readEntries(ctx, r, jobs, inFlight)
+	}()
+
+	// Décodage et prétraitement des entrées
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result := preprocessJob(job, opts)
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Remise des entrées dans l'ordre de lecture
+	pending := make(map[int]pipelineResult)
+	next := 0
+	for result := range results {
+		pending[result.seq] = result
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := handle(ready); err != nil {
+				return err
+			}
+			<-inFlight
+		}
+	}
+	return <-readErr
+}
+
+// Fonction pour lire le JSON source ([][]DataEntryRaw) entrée par entrée, sans les décoder.
+// Chaque entrée réserve une place dans inFlight avant d'être transmise.
+func readEntries(ctx context.Context, r io.Reader, jobs chan<- pipelineJob, inFlight chan<- struct{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	// Tableau extérieur (null : aucune entrée)
+	open, err := openArray(decoder)
+	if err != nil || !open {
+		return err
+	}
+
+	seq := 0
+	for outerIndex := 0; decoder.More(); outerIndex++ {
+		open, err := openArray(decoder)
+		if err != nil {
+			return err
+		}
+		if !open {
+			continue
+		}
+		for entryIndex := 0; decoder.More(); entryIndex++ {
+			var data json.RawMessage
+			if err := decoder.Decode(&data); err != nil {
+				return fmt.Errorf("décodage du JSON: entrée [%d][%d]: %w", outerIndex, entryIndex, err)
+			}
+			select {
+			case inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case jobs <- pipelineJob{seq: seq, outerIndex: outerIndex, entryIndex: entryIndex, end: decoder.InputOffset(), data: data}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			seq++
+		}
+		if err := closeArray(decoder); err != nil {
+			return err
+		}
+	}
+	return closeArray(decoder)
+}
+
+// Fonction auxiliaire pour lire le début d'un tableau JSON ; retourne false pour null
+func openArray(decoder *json.Decoder) (bool, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return false, fmt.Errorf("décodage du JSON: %w", err)
+	}
+	switch token {
+	case json.Delim('['):
+		return true, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("décodage du JSON: tableau attendu à l'octet %d, trouvé %v", decoder.InputOffset(), token)
+}
+
+// Fonction auxiliaire pour lire la fin d'un tableau JSON
+func closeArray(decoder *json.Decoder) error {
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("décodage du JSON: %w", err)
+	}
+	return nil
+}
+
+// Fonction pour décoder et prétraiter une entrée, dans un worker. Les erreurs propres à
+// l'entrée sont de type *EntryError (StageDecode ou StageValues).
+func preprocessJob(job pipelineJob, opts Options) pipelineResult {
+	result := pipelineResult{seq: job.seq, outerIndex: job.outerIndex, entryIndex: job.entryIndex, end: job.end}
+
+	// L'entrée a déjà été validée par le décodeur du goroutine de lecture : elle est
+	// découpée directement en champs, sans nouvelle analyse complète
+	var rawEntry DataEntryRaw
+	if err := rawEntry.UnmarshalJSON(job.data); err != nil {
+		result.err = decodeError(rawEntry.C, job, fmt.Errorf("décodage du JSON: %w", err))
+		return result
+	}
+	// En mode strict, les champs inconnus signalent une évolution du schéma non prise en charge
+	if opts.StrictFields {
+		if err := checkEntryFields(rawEntry, job.outerIndex, job.entryIndex); err != nil {
+			result.err = decodeError(rawEntry.C, job, fmt.Errorf("schéma JSON: %w", err))
+			return result
+		}
+	}
+	result.entry, result.err = preprocessEntry(rawEntry, job.outerIndex, job.entryIndex, opts)
+	return result
+}
+
+// Fonction auxiliaire pour décrire une entrée qui n'a pas pu être décodée
+func decodeError(channel string, job pipelineJob, err error) *EntryError {
+	return &EntryError{
+		Channel:    channel,
+		OuterIndex: job.outerIndex,
+		EntryIndex: job.entryIndex,
+		Row:        -1,
+		Col:        -1,
+		Stage:      StageDecode,
+		Err:        err,
+	}
+}
@@ -0,0 +1,167 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Fonction auxiliaire pour générer un JSON source de outer tableaux de n entrées, nommées
+// "<i>.<j>"
+func pipelineInput(outer, n int) string {
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < outer; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("[")
+		for j := 0; j < n; j++ {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, `{"c": "%d.%d", "v": [[%d, "%d"]]}`, i, j, 1000*(j+1), j)
+		}
+		b.WriteString("]")
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// Les entrées sont remises dans l'ordre du fichier, quel que soit le nombre de workers,
+// avec la position de leur fin dans le JSON source
+func TestRunPipelineOrder(t *testing.T) {
+	input := pipelineInput(3, 50)
+	for _, workers := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Workers = workers
+			var got []string
+			var end int64
+			err := runPipeline(context.Background(), strings.NewReader(input), opts, func(item pipelineResult) error {
+				if item.err != nil {
+					return item.err
+				}
+				if want := fmt.Sprintf("%d.%d", item.outerIndex, item.entryIndex); item.entry.C != want {
+					t.Errorf("entrée %d: '%s' à la position %s", item.seq, item.entry.C, want)
+				}
+				if item.seq != len(got) {
+					t.Errorf("entrée '%s': numéro %d, attendu %d", item.entry.C, item.seq, len(got))
+				}
+				if item.end <= end || !strings.HasSuffix(input[:item.end], `"]]}`) {
+					t.Errorf("entrée '%s': fin à la position %d, après %d", item.entry.C, item.end, end)
+				}
+				end = item.end
+				got = append(got, item.entry.C)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("pipeline: %v", err)
+			}
+			if len(got) != 150 {
+				t.Errorf("%d entrées traitées, attendu 150", len(got))
+			}
+		})
+	}
+}
+
+// Erreurs de lecture du JSON et erreurs propres à une entrée
+func TestRunPipelineErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		handled []string // entrées reçues, "!" pour une entrée en erreur
+		err     string   // extrait de l'erreur retournée ("" : pas d'erreur)
+	}{
+		{name: "null", input: `null`},
+		{name: "tableau intérieur null", input: `[null, [{"c": "a"}]]`, handled: []string{"a"}},
+		{name: "objet au lieu d'un tableau", input: `{"c": "a"}`, err: "tableau attendu"},
+		{name: "entrée au lieu d'un tableau", input: `[{"c": "a"}]`, err: "tableau attendu"},
+		{
+			name:    "JSON tronqué",
+			input:   `[[{"c": "a"}, {"c": "b"}, {"c": `,
+			handled: []string{"a", "b"},
+			err:     "décodage du JSON",
+		},
+		{
+			name:    "syntaxe invalide après des entrées",
+			input:   `[[{"c": "a"}], [{"c": "b"}}]`,
+			handled: []string{"a", "b"},
+			err:     "décodage du JSON",
+		},
+		{
+			name:    "entrée illisible",
+			input:   `[[{"c": "a"}, {"c": "b", "la": 300}, {"c": "c", "v": 3}, {"c": "d"}]]`,
+			handled: []string{"a", "!", "!", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Workers = 2
+			var handled []string
+			err := runPipeline(context.Background(), strings.NewReader(tt.input), opts, func(item pipelineResult) error {
+				if item.err != nil {
+					var entryErr *EntryError
+					if !errors.As(item.err, &entryErr) || entryErr.Stage != StageDecode || entryErr.EntryIndex != item.entryIndex {
+						t.Errorf("entrée [%d][%d]: erreur %v, attendu *EntryError à l'étape %q",
+							item.outerIndex, item.entryIndex, item.err, StageDecode)
+					}
+					handled = append(handled, "!")
+					return nil
+				}
+				handled = append(handled, item.entry.C)
+				return nil
+			})
+			if !reflect.DeepEqual(handled, tt.handled) {
+				t.Errorf("entrées reçues %q, attendu %q", handled, tt.handled)
+			}
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("erreur %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("erreur %v, attendu une erreur contenant %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// Une erreur de l'appelant ou l'annulation du contexte interrompt le pipeline sans
+// attendre la fin de la lecture
+func TestRunPipelineStop(t *testing.T) {
+	stop := errors.New("arrêt")
+	tests := []struct {
+		name   string
+		handle func(cancel context.CancelFunc) error
+		want   error
+	}{
+		{name: "erreur de l'appelant", handle: func(context.CancelFunc) error { return stop }, want: stop},
+		{name: "annulation", handle: func(cancel context.CancelFunc) error { cancel(); return nil }, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			opts := DefaultOptions()
+			opts.Workers = 2
+			handled := 0
+			err := runPipeline(ctx, strings.NewReader(pipelineInput(1, 1000)), opts, func(item pipelineResult) error {
+				handled++
+				if item.seq == 10 {
+					return tt.handle(cancel)
+				}
+				return ctx.Err()
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("erreur %v, attendu %v", err, tt.want)
+			}
+			// Au plus une entrée reçue après l'annulation, qui retourne alors l'erreur
+			if handled > 12 {
+				t.Errorf("%d entrées reçues après l'arrêt à la 11e", handled)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Lecture des fichiers produits par le convertisseur : attributs, enregistrements composés
+// et index. Les données sont lues dans leur type HDF5 de stockage puis décodées en Go ;
+// les fichiers sont supposés écrits sur une machine de même ordre des octets.
+
+// Objet HDF5 dont on peut lire les attributs : *hdf5.File, *hdf5.Group ou *hdf5.Dataset
+type attributeReader interface {
+	NumAttributes() (int, error)
+	AttributeNameByIndex(idx uint) (string, error)
+	OpenAttribute(name string) (*hdf5.Attribute, error)
+}
+
+// Fonction auxiliaire pour lister les noms des attributs d'un objet, par ordre alphabétique
+func attributeNames(obj attributeReader) ([]string, error) {
+	n, err := obj.NumAttributes()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, n)
+	for i := range names {
+		if names[i], err = obj.AttributeNameByIndex(uint(i)); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// Fonction pour lire un attribut et le convertir en valeur Go : int64 (uint64 pour les
+// entiers non signés de 64 bits), float64, string ou bool (énumération FALSE/TRUE),
+// ou []interface{} de ces valeurs pour les attributs 1-D. Les autres membres
+// d'énumération sont retournés par leur nom.
+func readAttribute(obj attributeReader, name string) (interface{}, error) {
+	attr, err := obj.OpenAttribute(name)
+	if err != nil {
+		return nil, err
+	}
+	defer attr.Close()
+
+	dtype, err := attr.Datatype()
+	if err != nil {
+		return nil, err
+	}
+	defer dtype.Close()
+
+	space := attr.Space()
+	if space == nil {
+		return nil, fmt.Errorf("espace de données de l'attribut '%s' inaccessible", name)
+	}
+	defer space.Close()
+
+	var n int
+	switch space.SimpleExtentType() {
+	case hdf5.S_NULL:
+		return []interface{}{}, nil
+	case hdf5.S_SCALAR:
+		n = 1
+	default:
+		n = space.SimpleExtentNPoints()
+	}
+	scalar := space.SimpleExtentType() == hdf5.S_SCALAR
+
+	var values []interface{}
+	switch {
+	case dtype.Class() == hdf5.T_STRING && dtype.IsVariableStr():
+		if scalar {
+			var s string
+			if err := attr.Read(&s, dtype); err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+		strs := make([]string, n)
+		if err := attr.Read(&strs, dtype); err != nil {
+			return nil, err
+		}
+		for _, s := range strs {
+			values = append(values, s)
+		}
+
+	case dtype.Class() == hdf5.T_STRING, dtype.Class() == hdf5.T_INTEGER,
+		dtype.Class() == hdf5.T_FLOAT, dtype.Class() == hdf5.T_ENUM:
+		size := int(dtype.Size())
+		buf := make([]byte, n*size)
+		if err := attr.Read(&buf, dtype); err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			value, err := decodeValue(dtype, buf[i*size:(i+1)*size])
+			if err != nil {
+				return nil, fmt.Errorf("attribut '%s': %v", name, err)
+			}
+			values = append(values, value)
+		}
+
+	default:
+		return nil, fmt.Errorf("attribut '%s': classe de type HDF5 non prise en charge (%d)", name, dtype.Class())
+	}
+
+	if scalar {
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// Fonction auxiliaire pour décoder une valeur scalaire stockée dans le type dtype
+func decodeValue(dtype *hdf5.Datatype, data []byte) (interface{}, error) {
+	switch dtype.Class() {
+	case hdf5.T_STRING:
+		if end := bytes.IndexByte(data, 0); end >= 0 {
+			data = data[:end]
+		}
+		return string(data), nil
+
+	case hdf5.T_INTEGER:
+		return decodeInteger(data, dtype.IsSigned()), nil
+
+	case hdf5.T_FLOAT:
+		switch len(data) {
+		case 4:
+			return float64(math.Float32frombits(binary.NativeEndian.Uint32(data))), nil
+		case 8:
+			return math.Float64frombits(binary.NativeEndian.Uint64(data)), nil
+		}
+
+	case hdf5.T_ENUM:
+		name, err := enumMemberName(dtype, data)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "FALSE":
+			return false, nil
+		case "TRUE":
+			return true, nil
+		}
+		return name, nil
+	}
+	return nil, fmt.Errorf("valeur de %d octets de classe %d non prise en charge", len(data), dtype.Class())
+}
+
+// Fonction auxiliaire pour décoder un entier de 1, 2, 4 ou 8 octets
+func decodeInteger(data []byte, signed bool) interface{} {
+	var u uint64
+	switch len(data) {
+	case 1:
+		u = uint64(data[0])
+	case 2:
+		u = uint64(binary.NativeEndian.Uint16(data))
+	case 4:
+		u = uint64(binary.NativeEndian.Uint32(data))
+	default:
+		u = binary.NativeEndian.Uint64(data)
+	}
+	if !signed {
+		if len(data) == 8 && u > math.MaxInt64 {
+			return u
+		}
+		return int64(u)
+	}
+	// Extension du signe
+	shift := 64 - 8*uint(len(data))
+	return int64(u<<shift) >> shift
+}
+
+// Fonction auxiliaire pour retrouver le nom du membre d'une énumération à partir de sa valeur
+func enumMemberName(dtype *hdf5.Datatype, data []byte) (string, error) {
+	enum := &hdf5.EnumType{Datatype: *dtype}
+	for i := 0; i < enum.NMembers(); i++ {
+		var value [8]byte
+		if err := enum.MemberValue(i, &value); err != nil {
+			return "", err
+		}
+		if bytes.Equal(value[:len(data)], data) {
+			return enum.MemberName(i), nil
+		}
+	}
+	return "", fmt.Errorf("valeur d'énumération inconnue %v", data)
+}
+
+// Fonction auxiliaire pour décrire un type composé lu dans un fichier sous forme de
+// recordLayout. Seuls les champs chaîne de longueur fixe, int64 et float64 sont pris en charge.
+func recordLayoutOf(dtype *hdf5.Datatype) (*recordLayout, error) {
+	if dtype.Class() != hdf5.T_COMPOUND {
+		return nil, fmt.Errorf("type composé attendu (classe %d)", dtype.Class())
+	}
+	compound := &hdf5.CompoundType{Datatype: *dtype}
+	layout := &recordLayout{Size: int(dtype.Size())}
+	for i := 0; i < compound.NMembers(); i++ {
+		ftype, err := compound.MemberType(i)
+		if err != nil {
+			return nil, err
+		}
+		field := recordField{Name: compound.MemberName(i), Size: int(ftype.Size()), Offset: compound.MemberOffset(i)}
+		switch {
+		case ftype.Class() == hdf5.T_STRING && !ftype.IsVariableStr():
+			field.Kind = reflect.String
+		case ftype.Class() == hdf5.T_INTEGER && field.Size == 8:
+			field.Kind = reflect.Int64
+		case ftype.Class() == hdf5.T_FLOAT && field.Size == 8:
+			field.Kind = reflect.Float64
+		default:
+			ftype.Close()
+			return nil, fmt.Errorf("champ '%s' de type non pris en charge", field.Name)
+		}
+		ftype.Close()
+		layout.Fields = append(layout.Fields, field)
+	}
+	return layout, nil
+}
+
+// Fonction auxiliaire pour trouver l'indice d'un champ d'un enregistrement, -1 s'il est absent
+func (l *recordLayout) fieldIndex(name string) int {
+	for i, field := range l.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Fonction pour lire tous les enregistrements d'un dataset 1-D de type composé
+func readDatasetRecords(dset *hdf5.Dataset) (*recordLayout, []byte, int, error) {
+	dtype, err := dset.Datatype()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer dtype.Close()
+	layout, err := recordLayoutOf(dtype)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	space := dset.Space()
+	if space == nil {
+		return nil, nil, 0, fmt.Errorf("espace de données de '%s' inaccessible", dset.Name())
+	}
+	defer space.Close()
+	n := space.SimpleExtentNPoints()
+	if n == 0 {
+		return layout, nil, 0, nil
+	}
+
+	buf := make([]byte, n*layout.Size)
+	if err := dset.Read(&buf); err != nil {
+		return nil, nil, 0, err
+	}
+	return layout, buf, n, nil
+}
+
+// Fonction pour lire les enregistrements d'un attribut 1-D de type composé (attribut "meta")
+func readAttributeRecords(obj attributeReader, name string) (*recordLayout, []byte, int, error) {
+	attr, err := obj.OpenAttribute(name)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer attr.Close()
+
+	dtype, err := attr.Datatype()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer dtype.Close()
+	layout, err := recordLayoutOf(dtype)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	space := attr.Space()
+	if space == nil {
+		return nil, nil, 0, fmt.Errorf("espace de données de l'attribut '%s' inaccessible", name)
+	}
+	defer space.Close()
+	n := space.SimpleExtentNPoints()
+	if n == 0 {
+		return layout, nil, 0, nil
+	}
+
+	buf := make([]byte, n*layout.Size)
+	if err := attr.Read(&buf, dtype); err != nil {
+		return nil, nil, 0, err
+	}
+	return layout, buf, n, nil
+}
+
+// Position d'une série dans le JSON d'origine, lue dans la table d'index
+type indexPosition struct {
+	OuterIndex int
+	EntryIndex int
+}
+
+// Fonction pour lire la table d'index global (si elle existe) : position de chaque série
+// dans le JSON d'origine, par chemin de dataset
+func readIndexPositions(f *hdf5.File) (map[string]indexPosition, error) {
+	positions := make(map[string]indexPosition)
+	if !f.LinkExists(IndexTableName) {
+		return positions, nil
+	}
+
+	dset, err := f.OpenDataset(IndexTableName)
+	if err != nil {
+		return nil, err
+	}
+	defer dset.Close()
+
+	layout, buf, n, err := readDatasetRecords(dset)
+	if err != nil {
+		return nil, fmt.Errorf("table '%s': %v", IndexTableName, err)
+	}
+	path, outer, entry := layout.fieldIndex("path"), layout.fieldIndex("outer_index"), layout.fieldIndex("entry_index")
+	if path < 0 || outer < 0 || entry < 0 {
+		return nil, fmt.Errorf("table '%s': champs path, outer_index ou entry_index absents", IndexTableName)
+	}
+	for i := 0; i < n; i++ {
+		record := buf[i*layout.Size:]
+		positions[layout.get(record, path).(string)] = indexPosition{
+			OuterIndex: int(layout.get(record, outer).(int64)),
+			EntryIndex: int(layout.get(record, entry).(int64)),
+		}
+	}
+	return positions, nil
+}
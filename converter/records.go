@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Enregistrements composés HDF5 dont tous les champs ont une taille fixe : chaînes UTF-8
+// de longueur fixe, int64 et float64. Contrairement aux chaînes de longueur variable,
+// ils se relisent sans manipuler de pointeurs C.
+
+// Champ d'un enregistrement composé
+type recordField struct {
+	Name   string
+	Kind   reflect.Kind // reflect.String, reflect.Int64 ou reflect.Float64
+	Size   int          // taille en octets (chaînes : longueur maximale + zéro terminal)
+	Offset int
+}
+
+// Disposition en mémoire (et dans le fichier) d'un enregistrement composé
+type recordLayout struct {
+	Fields []recordField
+	Size   int
+}
+
+// Champ chaîne pouvant contenir maxLen octets
+func stringField(name string, maxLen int) recordField {
+	return recordField{Name: name, Kind: reflect.String, Size: maxLen + 1}
+}
+
+func int64Field(name string) recordField {
+	return recordField{Name: name, Kind: reflect.Int64, Size: 8}
+}
+
+func float64Field(name string) recordField {
+	return recordField{Name: name, Kind: reflect.Float64, Size: 8}
+}
+
+// Fonction auxiliaire pour placer les champs les uns à la suite des autres, sans remplissage
+func newRecordLayout(fields ...recordField) *recordLayout {
+	layout := &recordLayout{Fields: fields}
+	for i := range layout.Fields {
+		layout.Fields[i].Offset = layout.Size
+		layout.Size += layout.Fields[i].Size
+	}
+	return layout
+}
+
+// Fonction auxiliaire pour créer le type composé HDF5 de l'enregistrement.
+// Le type retourné doit être fermé par l'appelant.
+func (l *recordLayout) datatype() (*hdf5.CompoundType, error) {
+	dtype, err := hdf5.NewCompoundType(l.Size)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range l.Fields {
+		var ftype *hdf5.Datatype
+		switch field.Kind {
+		case reflect.String:
+			ftype, err = newFixedStringType(field.Size)
+		case reflect.Int64:
+			ftype, err = hdf5.NewDatatypeFromValue(int64(0))
+		default:
+			ftype, err = hdf5.NewDatatypeFromValue(float64(0))
+		}
+		if err != nil {
+			dtype.Close()
+			return nil, err
+		}
+		err = dtype.Insert(field.Name, field.Offset, ftype)
+		ftype.Close()
+		if err != nil {
+			dtype.Close()
+			return nil, err
+		}
+	}
+	return dtype, nil
+}
+
+// Fonction auxiliaire pour écrire la valeur d'un champ dans un enregistrement
+func (l *recordLayout) set(record []byte, field int, value interface{}) {
+	f := l.Fields[field]
+	data := record[f.Offset : f.Offset+f.Size]
+	switch v := value.(type) {
+	case string:
+		copy(data[:f.Size-1], v)
+	case int64:
+		binary.NativeEndian.PutUint64(data, uint64(v))
+	case float64:
+		binary.NativeEndian.PutUint64(data, math.Float64bits(v))
+	}
+}
+
+// Fonction auxiliaire pour lire la valeur d'un champ d'un enregistrement
+func (l *recordLayout) get(record []byte, field int) interface{} {
+	f := l.Fields[field]
+	data := record[f.Offset : f.Offset+f.Size]
+	switch f.Kind {
+	case reflect.String:
+		if end := bytes.IndexByte(data, 0); end >= 0 {
+			data = data[:end]
+		}
+		return string(data)
+	case reflect.Int64:
+		return int64(binary.NativeEndian.Uint64(data))
+	default:
+		return math.Float64frombits(binary.NativeEndian.Uint64(data))
+	}
+}
+
+// Enregistrement déjà encodé, pour la table de paquets HDF5 (interface cmem.CMarshaler)
+type packedRecord []byte
+
+func (r packedRecord) MarshalC() ([]byte, error) {
+	return r, nil
+}
+
+// Fonction auxiliaire pour créer un type chaîne UTF-8 de longueur fixe (zéro compris).
+// Le type retourné doit être fermé par l'appelant.
+func newFixedStringType(size int) (*hdf5.Datatype, error) {
+	stype, err := hdf5.T_C_S1.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if err := stype.SetSize(size); err != nil {
+		stype.Close()
+		return nil, err
+	}
+	if err := stype.SetCharSet(hdf5.T_CSET_UTF8); err != nil {
+		stype.Close()
+		return nil, err
+	}
+	return stype, nil
+}
@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Aller-retour de textes non ASCII : attributs chaînes et champs chaînes de la table
+// d'index doivent être relus à l'identique et déclarés en UTF-8
+func TestUTF8RoundTrip(t *testing.T) {
+	const (
+		channel = "température.moteur"
+		site    = "Zürich — café «nord»"
+		unit    = "°C"
+	)
+	input := `[[{"c": "` + channel + `", "l": {"site": "` + site + `", "unit": "` + unit + `"}, "la": 1, "v": [[1000, 1.5], [2000, 2.5]]}]]`
+
+	f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "utf8.h5"), hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatalf("création du fichier: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := Convert(context.Background(), strings.NewReader(input), f, DefaultOptions()); err != nil {
+		t.Fatalf("conversion: %v", err)
+	}
+
+	// Table d'index : champs "c" et "labels"
+	rows, err := readIndexRows(f)
+	if err != nil {
+		t.Fatalf("lecture de la table d'index: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("table d'index: %d lignes, attendu 1", len(rows))
+	}
+	if rows[0].C != channel {
+		t.Errorf("index, champ c: %q, attendu %q", rows[0].C, channel)
+	}
+	if !strings.Contains(rows[0].Labels, site) {
+		t.Errorf("index, champ labels: %q ne contient pas %q", rows[0].Labels, site)
+	}
+	for _, field := range []string{"c", "labels"} {
+		if cset := indexFieldCharSet(t, f, field); cset != hdf5.T_CSET_UTF8 {
+			t.Errorf("index, champ %s: jeu de caractères %v, attendu UTF-8", field, cset)
+		}
+	}
+
+	// Attributs chaînes du dataset
+	dset, err := f.OpenDataset(strings.TrimPrefix(rows[0].Path, "/"))
+	if err != nil {
+		t.Fatalf("ouverture du dataset '%s': %v", rows[0].Path, err)
+	}
+	defer dset.Close()
+
+	for name, want := range map[string]string{"l_site": site, "l_unit": unit} {
+		value, err := readAttribute(dset, name)
+		if err != nil {
+			t.Fatalf("lecture de l'attribut '%s': %v", name, err)
+		}
+		if value != want {
+			t.Errorf("attribut '%s': %q, attendu %q", name, value, want)
+		}
+		if cset := attributeCharSet(t, dset, name); cset != hdf5.T_CSET_UTF8 {
+			t.Errorf("attribut '%s': jeu de caractères %v, attendu UTF-8", name, cset)
+		}
+	}
+}
+
+// Fonction auxiliaire pour lire le jeu de caractères d'un attribut chaîne
+func attributeCharSet(t *testing.T, dset *hdf5.Dataset, name string) hdf5.CharSet {
+	t.Helper()
+	attr, err := dset.OpenAttribute(name)
+	if err != nil {
+		t.Fatalf("ouverture de l'attribut '%s': %v", name, err)
+	}
+	defer attr.Close()
+
+	dtype, err := attr.Datatype()
+	if err != nil {
+		t.Fatalf("type de l'attribut '%s': %v", name, err)
+	}
+	defer dtype.Close()
+	return dtype.CharSet()
+}
+
+// Fonction auxiliaire pour lire le jeu de caractères d'un champ de la table d'index
+func indexFieldCharSet(t *testing.T, f *hdf5.File, field string) hdf5.CharSet {
+	t.Helper()
+	dset, err := f.OpenDataset(IndexTableName)
+	if err != nil {
+		t.Fatalf("ouverture de la table d'index: %v", err)
+	}
+	defer dset.Close()
+
+	dtype, err := dset.Datatype()
+	if err != nil {
+		t.Fatalf("type de la table d'index: %v", err)
+	}
+	defer dtype.Close()
+
+	compound := &hdf5.CompoundType{Datatype: *dtype}
+	i := compound.MemberIndex(field)
+	if i < 0 {
+		t.Fatalf("table d'index: champ '%s' absent", field)
+	}
+	ftype, err := compound.MemberType(i)
+	if err != nil {
+		t.Fatalf("type du champ '%s': %v", field, err)
+	}
+	defer ftype.Close()
+	return ftype.CharSet()
+}
@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ValidateReport décrit les séries que la conversion écrirait (voir ValidateInput)
+type ValidateReport struct {
+	Entries         int               // séries qui seraient écrites
+	Empty           int               // entrées sans données, ignorées
+	Renamed         int               // séries écrites sous un autre nom que "c"
+	Warnings        int               // valeurs de V non converties (remplacées par 0.0)
+	ChannelWarnings []ChannelWarnings // valeurs non converties, par série et par type
+	UnknownFields   int               // entrées contenant des champs inconnus, conservés en attributs x_*
+	FirstUnknown    error             // description de la première d'entre elles
+	Failures        []Failure         // entrées illisibles ou rejetées en mode strict (mode keep-going)
+}
+
+// ValidateInput lit le JSON source depuis r avec les mêmes règles que Convert (lecture
+// au fil de l'eau, mode strict, conversion des valeurs), sans rien écrire, et décrit les
+// séries que la conversion écrirait. Les erreurs d'une entrée sont de type *EntryError ;
+// en mode keep-going, elles sont décrites dans report.Failures et la lecture continue.
+func ValidateInput(ctx context.Context, r io.Reader, opts Options) (ValidateReport, error) {
+	var report ValidateReport
+	if err := opts.Validate(); err != nil {
+		return report, err
+	}
+
+	// Noms des séries, attribués par tableau extérieur comme lors de la conversion
+	var datasetNames map[string]int
+	currentOuter := -1
+
+	err := runPipeline(ctx, r, opts, func(item pipelineResult) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if item.err != nil {
+			var entryErr *EntryError
+			if !opts.KeepGoing || !errors.As(item.err, &entryErr) {
+				return item.err
+			}
+			report.Failures = append(report.Failures, newFailure(entryErr))
+			return nil
+		}
+		entry := item.entry
+		if item.outerIndex != currentOuter {
+			datasetNames = map[string]int{IndexTableName: 0}
+			currentOuter = item.outerIndex
+		}
+
+		if len(entry.X) > 0 {
+			if report.UnknownFields == 0 {
+				report.FirstUnknown = checkEntryFields(DataEntryRaw{C: entry.C, X: entry.X}, item.outerIndex, item.entryIndex)
+			}
+			report.UnknownFields++
+		}
+		if len(entry.V) == 0 {
+			report.Empty++
+			return nil
+		}
+
+		name := entryDatasetName(datasetNames, entry, opts)
+		report.Entries++
+		report.Warnings += entry.Warnings
+		if name != entry.C {
+			report.Renamed++
+		}
+		if len(entry.WarningCounts) > 0 {
+			report.ChannelWarnings = append(report.ChannelWarnings, ChannelWarnings{Channel: entry.C, Path: "/" + name, Counts: entry.WarningCounts})
+		}
+		return nil
+	})
+	return report, err
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// La validation décrit les séries comme la conversion les écrirait, et rejette les mêmes
+// entrées qu'elle
+func TestValidateInput(t *testing.T) {
+	const input = `[[` +
+		`{"c": "a", "v": [[1000, 1]]},` +
+		`{"c": "a", "u": "°C", "v": [[1000, "x"]]},` +
+		`{"c": "vide", "v": []},` +
+		`{"c": "b", "la": 300, "v": [[1000, 2]]}` +
+		`], [` +
+		`{"c": "a", "src": "capteur", "v": [[1000, 3]]}` +
+		`]]`
+
+	tests := []struct {
+		name     string
+		options  func(*Options)
+		want     ValidateReport // FirstUnknown et ChannelWarnings non comparés
+		failures []string       // étapes des entrées rejetées
+		stage    string         // étape de l'erreur attendue ("" : pas d'erreur)
+	}{
+		{
+			name:    "entrée illisible",
+			options: func(opts *Options) {},
+			stage:   StageDecode,
+		},
+		{
+			name:     "mode keep-going",
+			options:  func(opts *Options) { opts.KeepGoing = true },
+			want:     ValidateReport{Entries: 3, Empty: 1, Renamed: 1, Warnings: 1, UnknownFields: 2},
+			failures: []string{StageDecode},
+		},
+		{
+			name: "mode strict",
+			options: func(opts *Options) {
+				opts.KeepGoing = true
+				opts.StrictFields = true
+				opts.StrictValues = true
+			},
+			want:     ValidateReport{Entries: 1, Empty: 1},
+			failures: []string{StageDecode, StageDecode, StageDecode},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.options(&opts)
+			report, err := ValidateInput(context.Background(), strings.NewReader(input), opts)
+			if tt.stage != "" {
+				var entryErr *EntryError
+				if !errors.As(err, &entryErr) || entryErr.Stage != tt.stage {
+					t.Fatalf("erreur %v, attendu *EntryError à l'étape %q", err, tt.stage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validation: %v", err)
+			}
+
+			var stages []string
+			for _, failure := range report.Failures {
+				stages = append(stages, failure.Stage)
+			}
+			if strings.Join(stages, ",") != strings.Join(tt.failures, ",") {
+				t.Errorf("entrées rejetées aux étapes %q, attendu %q", stages, tt.failures)
+			}
+			got := report
+			got.FirstUnknown, got.ChannelWarnings, got.Failures = nil, nil, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rapport %+v, attendu %+v", got, tt.want)
+			}
+			if (report.UnknownFields > 0) != (report.FirstUnknown != nil) {
+				t.Errorf("première entrée à champs inconnus %v pour %d entrées", report.FirstUnknown, report.UnknownFields)
+			}
+		})
+	}
+
+	// Une erreur de syntaxe interrompt la validation, même en mode keep-going
+	opts := DefaultOptions()
+	opts.KeepGoing = true
+	if _, err := ValidateInput(context.Background(), strings.NewReader(`[[{"c": "a", "v": [[1000, 1]]},`), opts); err == nil {
+		t.Error("JSON tronqué accepté")
+	}
+}
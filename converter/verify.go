@@ -0,0 +1,277 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+
+	"gonum.org/v1/hdf5"
+)
+
+// VerifyMismatch est une différence constatée sur une série
+type VerifyMismatch struct {
+	Name   string
+	Reason string
+}
+
+// VerifyReport est le résultat de la vérification d'un fichier
+type VerifyReport struct {
+	Checked    int              // séries attendues
+	Missing    []string         // séries attendues absentes du fichier
+	Extra      []string         // datasets du fichier qui ne correspondent à aucune série
+	Mismatched []VerifyMismatch // différences, plusieurs possibles par série
+}
+
+// OK indique si le fichier est conforme au JSON source
+func (r *VerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+// MismatchedCount retourne le nombre de séries présentant au moins une différence
+func (r *VerifyReport) MismatchedCount() int {
+	names := make(map[string]bool)
+	for _, mismatch := range r.Mismatched {
+		names[mismatch.Name] = true
+	}
+	return len(names)
+}
+
+// VerifyFile relit le JSON source depuis r avec les mêmes règles que Convert (lecture au
+// fil de l'eau, conversion des valeurs) et compare chaque série attendue au contenu du
+// fichier, écrit avec les mêmes options (formats matrix, compound et mat ; les fichiers
+// aux formats cf et pandas donnent une erreur ErrUnsupportedFormat). Les champs
+// inconnus sont attendus en attributs x_*, même en mode strict ; en mode keep-going, les
+// entrées illisibles ou rejetées ne sont pas attendues dans le fichier. tolerance est
+// l'écart relatif toléré entre valeurs flottantes.
+func VerifyFile(ctx context.Context, r io.Reader, f *hdf5.File, opts Options, tolerance float64) (*VerifyReport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := checkReadableFormat(f, opts.Format); err != nil {
+		return nil, err
+	}
+	opts.StrictFields = false
+
+	report := &VerifyReport{}
+	expected := make(map[string]bool)
+
+	// Mêmes noms que lors de la conversion, attribués par tableau extérieur
+	var datasetNames map[string]int
+	currentOuter := -1
+
+	err := runPipeline(ctx, r, opts, func(item pipelineResult) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if item.err != nil {
+			var entryErr *EntryError
+			if opts.KeepGoing && errors.As(item.err, &entryErr) {
+				return nil
+			}
+			return item.err
+		}
+		entry := item.entry
+		if item.outerIndex != currentOuter {
+			datasetNames = map[string]int{IndexTableName: 0}
+			currentOuter = item.outerIndex
+		}
+		if len(entry.V) == 0 {
+			return nil
+		}
+		name := entryDatasetName(datasetNames, entry, opts)
+		expected[name] = true
+		report.Checked++
+
+		if !f.LinkExists(name) {
+			report.Missing = append(report.Missing, name)
+			return nil
+		}
+		for _, reason := range verifyEntry(f, name, entry, opts.TimestampDivisor, tolerance) {
+			report.Mismatched = append(report.Mismatched, VerifyMismatch{Name: name, Reason: reason})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Datasets du fichier sans série correspondante
+	n, err := f.NumObjects()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint(0); i < n; i++ {
+		name, err := f.ObjectNameByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		if !expected[name] && name != IndexTableName {
+			report.Extra = append(report.Extra, name)
+		}
+	}
+	sort.Strings(report.Extra)
+	return report, nil
+}
+
+// Fonction pour comparer une série au dataset name. Retourne la liste des différences.
+func verifyEntry(f *hdf5.File, name string, entry DataEntryFloat, timestampDivisor, tolerance float64) []string {
+	dset, err := f.OpenDataset(name)
+	if err != nil {
+		return []string{fmt.Sprintf("ouverture impossible: %v", err)}
+	}
+	defer dset.Close()
+
+	var reasons []string
+
+	// Dimensions et valeurs
+	rows, ok, err := readEntryRows(dset, timestampDivisor)
+	switch {
+	case err != nil:
+		reasons = append(reasons, fmt.Sprintf("lecture impossible: %v", err))
+	case !ok:
+		reasons = append(reasons, "format de dataset non reconnu")
+	case len(rows) != len(entry.V) || len(rows[0]) != len(entry.V[0]):
+		cols := 0
+		if len(rows) > 0 {
+			cols = len(rows[0])
+		}
+		reasons = append(reasons, fmt.Sprintf("dimensions %dx%d, attendu %dx%d", len(rows), cols, len(entry.V), len(entry.V[0])))
+	default:
+		if reason := compareRows(rows, entry.V, tolerance); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	// Attributs
+	return append(reasons, verifyAttributes(dset, name, entry, tolerance)...)
+}
+
+// Fonction auxiliaire pour comparer les valeurs d'une série ; retourne la première différence
+func compareRows(rows, expected [][]float64, tolerance float64) string {
+	for i, row := range expected {
+		for j, value := range row {
+			if !floatsEqual(rows[i][j], value, tolerance) {
+				return fmt.Sprintf("valeur [%d][%d] = %v, attendu %v", i, j, rows[i][j], value)
+			}
+		}
+	}
+	return ""
+}
+
+// Fonction auxiliaire pour comparer deux flottants avec un écart relatif toléré (NaN égal à NaN)
+func floatsEqual(a, b, tolerance float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) <= tolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// Fonction auxiliaire pour comparer les attributs d'un dataset à ceux que la conversion
+// écrit pour l'entrée : original_name, la et les métadonnées, en attributs individuels
+// et/ou dans l'attribut composé "meta" selon ce que contient le fichier
+func verifyAttributes(dset *hdf5.Dataset, name string, entry DataEntryFloat, tolerance float64) []string {
+	var reasons []string
+
+	addStateAttributes(&entry)
+	metadata, _ := entryMetadata(entry)
+
+	expected := []namedAttribute{{Name: "la", Value: entry.La}}
+	if name != entry.C {
+		expected = append(expected, namedAttribute{Name: "original_name", Value: entry.C})
+	}
+
+	// Les métadonnées sont attendues en attributs individuels, sauf si le fichier
+	// ne contient que l'attribut "meta"
+	hasRecord := dset.AttributeExists(metadataRecordName)
+	inAttributes := !hasRecord
+	for _, attr := range metadata {
+		if dset.AttributeExists(attr.Name) {
+			inAttributes = true
+			break
+		}
+	}
+	if inAttributes {
+		expected = append(expected, metadata...)
+	}
+
+	for _, attr := range expected {
+		if !dset.AttributeExists(attr.Name) {
+			reasons = append(reasons, fmt.Sprintf("attribut '%s' manquant", attr.Name))
+			continue
+		}
+		value, err := readAttribute(dset, attr.Name)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("attribut '%s' illisible: %v", attr.Name, err))
+			continue
+		}
+		if !attributeValuesEqual(value, attr.Value, tolerance) {
+			reasons = append(reasons, fmt.Sprintf("attribut '%s' = %v, attendu %v", attr.Name, value, attr.Value))
+		}
+	}
+
+	if hasRecord {
+		if reason := verifyMetadataRecord(dset, metadata); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// Fonction auxiliaire pour comparer l'attribut composé "meta" aux métadonnées attendues
+func verifyMetadataRecord(dset *hdf5.Dataset, metadata []namedAttribute) string {
+	layout, buf, n, err := readAttributeRecords(dset, metadataRecordName)
+	if err != nil {
+		return fmt.Sprintf("attribut '%s' illisible: %v", metadataRecordName, err)
+	}
+	if n != len(metadata) {
+		return fmt.Sprintf("attribut '%s': %d enregistrements, attendu %d", metadataRecordName, n, len(metadata))
+	}
+	for i, attr := range metadata {
+		record := buf[i*layout.Size:]
+		got := []interface{}{layout.get(record, 0), layout.get(record, 1), layout.get(record, 2)}
+		want := []interface{}{attr.Source, attr.Key, metadataText(attr.Value)}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Sprintf("attribut '%s': enregistrement %d = %v, attendu %v", metadataRecordName, i, got, want)
+		}
+	}
+	return ""
+}
+
+// Fonction auxiliaire pour comparer une valeur lue par readAttribute à la valeur Go écrite
+func attributeValuesEqual(value, expected interface{}, tolerance float64) bool {
+	rv := reflect.ValueOf(expected)
+	if rv.Kind() == reflect.Slice {
+		values, ok := value.([]interface{})
+		if !ok || len(values) != rv.Len() {
+			return false
+		}
+		for i := range values {
+			if !attributeValuesEqual(values[i], rv.Index(i).Interface(), tolerance) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, ok := value.(int64)
+		return ok && v == rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch v := value.(type) {
+		case int64:
+			return v >= 0 && uint64(v) == rv.Uint()
+		case uint64:
+			return v == rv.Uint()
+		}
+		return false
+	case reflect.Float32, reflect.Float64:
+		v, ok := value.(float64)
+		return ok && floatsEqual(v, rv.Float(), tolerance)
+	}
+	return value == expected
+}
@@ -0,0 +1,141 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Un fichier converti est conforme à son JSON source, et au JSON qu'en reconstruit
+// l'export ; les séries modifiées, manquantes ou en trop sont signalées
+func TestVerifyFile(t *testing.T) {
+	const input = `[[` +
+		`{"c": "a", "l": {"site": "nord", "mixed": [1, "x"], "vehicle": {"type": "bus"}}, "la": 2, "v": [[2000, 2.5], [1000, "x"]]},` +
+		`{"c": "a", "u": "°C", "v": [[1000, 3, 4]]},` +
+		`{"c": "vide", "v": []}` +
+		`]]`
+
+	tests := []struct {
+		name       string
+		options    func(*Options)
+		input      string // JSON vérifié ("" : JSON source)
+		missing    []string
+		extra      []string
+		mismatched []string
+	}{
+		{name: "matrix"},
+		{name: "compound", options: func(opts *Options) { opts.Format = FormatCompound }},
+		{name: "mat", options: func(opts *Options) { opts.Format = FormatMat }},
+		{name: "attribut meta", options: func(opts *Options) {
+			opts.MetadataInAttributes = false
+			opts.MetadataInRecord = true
+		}},
+		{
+			name:    "mode strict",
+			options: func(opts *Options) { opts.StrictFields = true },
+		},
+		{
+			name:       "valeurs et attributs différents",
+			input:      `[[{"c": "a", "l": {"site": "sud", "mixed": [1, "x"], "vehicle": {"type": "bus"}}, "la": 2, "v": [[2000, 2.5], [1000, 1]]}, {"c": "a", "u": "°C", "v": [[1000, 3, 4]]}]]`,
+			mismatched: []string{"a", "a"},
+		},
+		{
+			name:    "séries manquantes et en trop",
+			input:   `[[{"c": "a", "l": {"site": "nord", "mixed": [1, "x"], "vehicle": {"type": "bus"}}, "la": 2, "v": [[2000, 2.5], [1000, "x"]]}, {"c": "b", "v": [[1000, 1]]}]]`,
+			missing: []string{"b"},
+			extra:   []string{"a_1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tt.options != nil {
+				tt.options(&opts)
+			}
+			convertOpts := opts
+			convertOpts.StrictFields = false
+			f, _, err := convertString(t, input, convertOpts)
+			if err != nil {
+				t.Fatalf("conversion: %v", err)
+			}
+
+			verify := func(source, text string) {
+				t.Helper()
+				report, err := VerifyFile(context.Background(), strings.NewReader(text), f, opts, 1e-9)
+				if err != nil {
+					t.Fatalf("%s: vérification: %v", source, err)
+				}
+				var mismatched []string
+				for _, mismatch := range report.Mismatched {
+					mismatched = append(mismatched, mismatch.Name)
+				}
+				got := [][]string{report.Missing, report.Extra, mismatched}
+				want := [][]string{tt.missing, tt.extra, tt.mismatched}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: manquantes, en trop, différentes %q, attendu %q (%+v)", source, got, want, report.Mismatched)
+				}
+				if report.OK() != (tt.missing == nil && tt.extra == nil && tt.mismatched == nil) {
+					t.Errorf("%s: OK() = %v", source, report.OK())
+				}
+			}
+			if tt.input != "" {
+				verify("JSON modifié", tt.input)
+				return
+			}
+			verify("JSON source", input)
+
+			// Le JSON reconstruit par l'export est conforme au fichier
+			datasets, _, err := ExportFile(f, opts)
+			if err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			exported, err := json.Marshal(datasets)
+			if err != nil {
+				t.Fatalf("encodage: %v", err)
+			}
+			verify("JSON exporté", string(exported))
+		})
+	}
+}
+
+// Les fichiers aux formats cf et pandas sont rejetés par la vérification
+func TestVerifyUnsupportedFormat(t *testing.T) {
+	const input = `[[{"c": "a", "v": [[1000, 1]]}]]`
+	tests := []struct {
+		name   string
+		format string // format de la conversion
+		verify string // format déclaré à la vérification
+	}{
+		{name: "fichier cf", format: FormatCF, verify: FormatMatrix},
+		{name: "fichier pandas", format: FormatPandas, verify: FormatMatrix},
+		{name: "format cf demandé", format: FormatMatrix, verify: FormatCF},
+		{name: "format pandas demandé", format: FormatMatrix, verify: FormatPandas},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Format = tt.format
+			f, _, err := convertString(t, input, opts)
+			if err != nil {
+				t.Fatalf("conversion: %v", err)
+			}
+			switch tt.format {
+			case FormatCF:
+				err = WriteCFGlobalAttributes(f)
+			case FormatPandas:
+				err = WritePandasGlobalAttributes(f)
+			}
+			if err != nil {
+				t.Fatalf("attributs globaux: %v", err)
+			}
+			opts.Format = tt.verify
+			_, err = VerifyFile(context.Background(), strings.NewReader(input), f, opts, 1e-9)
+			if !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("vérification: erreur %v, attendu ErrUnsupportedFormat", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Types de valeurs de V non converties (remplacées par 0.0)
+const (
+	WarningUnparseableString = "unparseable_string" // chaîne qui n'est ni un nombre ni un état connu
+	WarningUnparseableNumber = "unparseable_number" // nombre JSON hors de la plage des float64
+	WarningUnsupportedType   = "unsupported_type"   // tableau ou objet JSON
+	WarningNull              = "null"               // valeur JSON null
+)
+
+// Préfixe des attributs portant le nombre de valeurs non converties d'un type
+const warningAttributePrefix = "warn_"
+
+// Nombre maximal d'exemples conservés par type d'avertissement
+const maxWarningExamples = 3
+
+// WarningCount compte les valeurs non converties d'un même type dans une série
+type WarningCount struct {
+	Kind     string   `json:"kind"`
+	Count    int      `json:"count"`
+	Examples []string `json:"examples"` // premiers exemples, "[ligne][colonne] valeur" dans le JSON
+}
+
+// ChannelWarnings regroupe les avertissements de conversion d'une série
+type ChannelWarnings struct {
+	Channel string         `json:"channel"`
+	Path    string         `json:"path"`
+	Counts  []WarningCount `json:"counts"`
+}
+
+// Fonction auxiliaire pour compter une valeur non convertie ; les types gardent l'ordre
+// de leur première apparition
+func addWarning(counts []WarningCount, kind, example string) []WarningCount {
+	for i := range counts {
+		if counts[i].Kind == kind {
+			counts[i].Count++
+			if len(counts[i].Examples) < maxWarningExamples {
+				counts[i].Examples = append(counts[i].Examples, example)
+			}
+			return counts
+		}
+	}
+	return append(counts, WarningCount{Kind: kind, Count: 1, Examples: []string{example}})
+}
+
+// Fonction auxiliaire pour décrire une valeur non convertie à la position [i][j]
+func warningExample(val interface{}, i, j int) string {
+	text := "null"
+	if val != nil {
+		if data, err := json.Marshal(val); err == nil {
+			text = string(data)
+		} else {
+			text = fmt.Sprintf("%v", val)
+		}
+	}
+	// Les exemples restent courts, même pour un objet volumineux
+	const maxExampleLength = 40
+	if runes := []rune(text); len(runes) > maxExampleLength {
+		text = string(runes[:maxExampleLength]) + "..."
+	}
+	return fmt.Sprintf("[%d][%d] %s", i, j, text)
+}
+
+// Fonction auxiliaire pour construire les attributs warn_<type> d'une entrée
+func warningAttributes(entry DataEntryFloat) []namedAttribute {
+	attrs := make([]namedAttribute, 0, len(entry.WarningCounts))
+	for _, count := range entry.WarningCounts {
+		attrs = append(attrs, namedAttribute{Name: warningAttributePrefix + count.Kind, Value: int64(count.Count)})
+	}
+	return attrs
+}
+
+// Fonction auxiliaire pour ajouter aux compteurs d'une entrée ceux d'un attribut warn_<type>
+// déjà présent dans le fichier (mode ajout) ; les exemples ne sont pas conservés
+func mergeWarningAttribute(counts []WarningCount, name string, value interface{}) []WarningCount {
+	n, ok := value.(int64)
+	if !ok || !strings.HasPrefix(name, warningAttributePrefix) {
+		return counts
+	}
+	kind := strings.TrimPrefix(name, warningAttributePrefix)
+	for i := range counts {
+		if counts[i].Kind == kind {
+			counts[i].Count += int(n)
+			return counts
+		}
+	}
+	return append(counts, WarningCount{Kind: kind, Count: int(n)})
+}
@@ -0,0 +1,531 @@
+package converter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Emplacement où sont créées les séries : fichier (groupe racine) ou groupe HDF5
+type location interface {
+	CreateDatasetWith(name string, dtype *hdf5.Datatype, dspace *hdf5.Dataspace, dcpl *hdf5.PropList) (*hdf5.Dataset, error)
+	CreateGroup(name string) (*hdf5.Group, error)
+	OpenDataset(name string) (*hdf5.Dataset, error)
+	LinkExists(name string) bool
+	Unlink(name string) error
+}
+
+// Fonction pour écrire une entrée (dataset et attributs) sous le nom name.
+// Retourne le type de stockage choisi, le nom du dataset principal et les noms des objets
+// créés, y compris en cas d'erreur (voir removeEntry).
+func writeEntry(loc location, name, baseName string, entry DataEntryFloat, opts Options) (hdf5.Layout, string, []string, error) {
+	// Ajouter les tables d'états connues aux attributs "a"
+	addStateAttributes(&entry)
+
+	// Pour "l", "a" et les champs inconnus de l'entrée (préfixe "x_") : convertir les maps
+	// en attributs, objets imbriqués aplatis
+	metadata, duplicates := entryMetadata(entry)
+	for _, attrName := range duplicates {
+		opts.logger().Warn("Métadonnée en double ignorée", "event", "duplicate_metadata", "channel", baseName, "path", "/"+name, "attribute", attrName)
+	}
+
+	// Attributs du dataset (voir writeEntryAttributes), pour choisir leur mode de stockage
+	// et celui des données
+	attrs := attributeSize{}.add("la", entry.La)
+	if name != baseName {
+		attrs = attrs.add("original_name", baseName)
+	}
+	if opts.MetadataInAttributes {
+		for _, attr := range metadata {
+			attrs = attrs.add(attr.Name, attr.Value)
+		}
+	}
+	if opts.MetadataInRecord && len(metadata) > 0 {
+		layout, _ := metadataRecordLayout(metadata)
+		attrs = attrs.addBytes(metadataRecordName, layout.Size*len(metadata))
+	}
+	// Attributs warn_<type> des valeurs non converties
+	for _, attr := range warningAttributes(entry) {
+		attrs = attrs.add(attr.Name, attr.Value)
+	}
+
+	// Datasets portant les données de la série (plusieurs variables en mode CF), et objets
+	// créés : le dataset ou le groupe pandas name, s'il n'existait pas avant l'écriture
+	var dsets []*hdf5.Dataset
+	var created []string
+	var layout hdf5.Layout
+	var err error
+	existed := loc.LinkExists(name)
+	switch opts.Format {
+	case FormatCompound:
+		var dset *hdf5.Dataset
+		dset, layout, err = writeCompoundDataset(loc, name, entry, attrs, opts)
+		dsets = []*hdf5.Dataset{dset}
+	case FormatCF:
+		dsets, created, layout, err = writeCFVariables(loc, name, entry, attrs, opts)
+	case FormatPandas:
+		var dset *hdf5.Dataset
+		dset, layout, err = writePandasTable(loc, name, entry, opts)
+		dsets = []*hdf5.Dataset{dset}
+	case FormatMat:
+		var dset *hdf5.Dataset
+		dset, layout, err = writeMatDataset(loc, name, entry, attrs, opts)
+		dsets = []*hdf5.Dataset{dset}
+	default:
+		var dset *hdf5.Dataset
+		dset, layout, err = writeMatrixDataset(loc, name, entry, attrs, opts)
+		dsets = []*hdf5.Dataset{dset}
+	}
+	if opts.Format != FormatCF && !existed && loc.LinkExists(name) {
+		created = []string{name}
+	}
+	if err != nil {
+		return hdf5.D_LAYOUT_ERROR, "", created, err
+	}
+
+	path := dsetName(dsets[0], name)
+	for _, dset := range dsets {
+		if err == nil {
+			err = writeEntryAttributes(dset, name, baseName, entry, metadata, opts)
+		}
+		dset.Close()
+	}
+	return layout, path, created, err
+}
+
+// Fonction pour supprimer les objets laissés par une écriture d'entrée interrompue
+// (mode keep-going) : le dataset, le groupe pandas ou les variables CF, tels que retournés
+// par writeEntry. Seuls les objets créés par cette écriture sont supprimés, jamais ceux
+// d'une autre série. L'espace occupé n'est pas récupéré dans le fichier.
+func removeEntry(loc location, created []string) error {
+	for _, objName := range created {
+		if !loc.LinkExists(objName) {
+			continue
+		}
+		if err := loc.Unlink(objName); err != nil {
+			return fmt.Errorf("suppression de '%s': %w", objName, err)
+		}
+	}
+	return nil
+}
+
+// Fonction pour écrire les attributs d'une entrée sur un de ses datasets
+func writeEntryAttributes(dset attributeHolder, name, baseName string, entry DataEntryFloat, metadata []namedAttribute, opts Options) error {
+	// Pour garder une trace de l'association avec le nom original
+	if name != baseName {
+		if err := writeAttribute(dset, "original_name", baseName); err != nil {
+			return stageError(StageAttributes, "ajout de l'attribut 'original_name': %w", err)
+		}
+	}
+
+	// Métadonnées en attributs individuels et/ou en un seul attribut composé "meta"
+	if opts.MetadataInAttributes {
+		for _, attr := range metadata {
+			if err := writeAttribute(dset, attr.Name, attr.Value); err != nil {
+				return stageError(StageAttributes, "ajout de l'attribut '%s': %w", attr.Name, err)
+			}
+		}
+	}
+	if opts.MetadataInRecord {
+		if err := writeMetadataRecord(dset, metadataRecordName, metadata); err != nil {
+			return stageError(StageAttributes, "ajout de l'attribut '%s': %w", metadataRecordName, err)
+		}
+	}
+
+	// Nombre de valeurs non converties, par type
+	for _, attr := range warningAttributes(entry) {
+		if err := writeAttribute(dset, attr.Name, attr.Value); err != nil {
+			return stageError(StageAttributes, "ajout de l'attribut '%s': %w", attr.Name, err)
+		}
+	}
+
+	// Pour "la"
+	if err := writeAttribute(dset, "la", entry.La); err != nil {
+		return stageError(StageAttributes, "ajout de l'attribut 'la': %w", err)
+	}
+	return nil
+}
+
+// Chemin complet d'un dataset dans le fichier
+func dsetName(dset *hdf5.Dataset, fallback string) string {
+	if name := dset.Name(); name != "" {
+		return name
+	}
+	return "/" + strings.TrimPrefix(fallback, "/")
+}
+
+// Fonction pour écrire une série sous forme de matrice 2-D rows×cols de float64
+func writeMatrixDataset(loc location, name string, entry DataEntryFloat, attrs attributeSize, opts Options) (*hdf5.Dataset, hdf5.Layout, error) {
+	// Déterminer les dimensions du dataset
+	rows := len(entry.V)
+	cols := len(entry.V[0])
+
+	// Créer un espace pour le dataset (nombre de lignes illimité en mode ajout)
+	dims := []uint{uint(rows), uint(cols)}
+	var maxDims []uint
+	if opts.Append {
+		maxDims = []uint{hdf5.S_UNLIMITED, uint(cols)}
+	}
+	space, err := hdf5.CreateSimpleDataspace(dims, maxDims)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de l'espace de données: %w", err)
+	}
+	defer space.Close()
+
+	var chunks []uint
+	switch opts.Chunking {
+	case ChunkingRow:
+		// Configurer le chunking par ligne
+		chunks = []uint{1, uint(cols)}
+	case ChunkingMatrix:
+		// Configurer le chunking sur la base de la taille de la matrice
+		chunks = []uint{uint(rows), uint(cols)}
+	default:
+		// Configuer le chunking par colonne
+		chunks = []uint{uint(rows), 1}
+	}
+
+	// Configurer le chunking de manière optimale
+	/*var chunks []uint
+	if rows > cols {
+		chunks = []uint{uint(min(rows, 100)), uint(cols)} // chunk par blocs de lignes
+	} else {
+		chunks = []uint{uint(rows), uint(min(cols, 100))} // chunk par blocs de colonnes
+	}*/
+
+	if opts.Chunking != ChunkingRow {
+		setAppendChunkRows(chunks, opts)
+	}
+
+	// Écriture par blocs de lignes des grandes séries, alignés sur les chunks
+	block := alignBlockRows(blockRows(rows, opts), chunks)
+
+	prop, layout, err := newDatasetPropList(rows, cols, chunks, attrs, opts)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+	defer prop.Close()
+
+	// Créer un dataset directement avec le nom "c" de type float64
+	dset, err := loc.CreateDatasetWith(name, hdf5.T_NATIVE_DOUBLE, space, prop)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du dataset '%s': %w", name, err)
+	}
+
+	// Écrire les données, chunk par chunk compressés en parallèle si demandé
+	direct := opts.DirectChunks && layout == hdf5.D_CHUNKED
+	if err := writeMatrixRows(dset, entry.V, cols, chunks, block, direct, opts); err != nil {
+		dset.Close()
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	return dset, layout, nil
+}
+
+// Fonction pour écrire les lignes d'une matrice par blocs de block lignes : chaque bloc
+// est aplati dans un même tampon, puis écrit par sélection hyperslab ou chunk par chunk
+// (direct)
+func writeMatrixRows(dset *hdf5.Dataset, rows [][]float64, cols int, chunks []uint, block int, direct bool, opts Options) error {
+	flatData := make([]float64, min(block, len(rows))*cols)
+	return writeBlocks(len(rows), block, func(start, n int) error {
+		// Convertir les données 2D en format plat pour HDF5 (zéros pour les lignes courtes)
+		data := flatData[:n*cols]
+		clear(data)
+		for i := 0; i < n; i++ {
+			copy(data[i*cols:(i+1)*cols], rows[start+i])
+		}
+
+		if direct {
+			written, err := writeChunksDirect(dset, data, []uint{uint(n), uint(cols)}, chunks, uint(start), workerCount(opts))
+			if err != nil {
+				return fmt.Errorf("écriture directe des chunks: %w", err)
+			}
+			if written {
+				return nil
+			}
+			// Filtres non pris en charge : écriture par la bibliothèque pour tous les blocs
+			direct = false
+		}
+		return writeRowsSubset(dset, uint(start), uint(n), uint(cols), &data)
+	})
+}
+
+// Fonction pour écrire les n lignes d'une série par blocs de block lignes : write écrit les
+// lignes start à start+count-1 (writeRowsSubset), dont les données ne sont préparées qu'au
+// moment de leur écriture
+func writeBlocks(n, block int, write func(start, count int) error) error {
+	for start := 0; start < n; start += block {
+		count := min(block, n-start)
+		if err := write(start, count); err != nil {
+			return stageError(StageWrite, "écriture des lignes %d à %d: %w", start, start+count-1, err)
+		}
+	}
+	return nil
+}
+
+// Fonction auxiliaire pour écrire data (n lignes) à partir de la ligne start d'un dataset
+// 1-D d'enregistrements (cols = 0) ou d'une matrice de cols colonnes, par sélection hyperslab.
+// La pile d'erreurs HDF5 d'un échec d'écriture est relevée (voir stageError).
+func writeRowsSubset(dset *hdf5.Dataset, start, n, cols uint, data interface{}) error {
+	offset, count := []uint{start}, []uint{n}
+	if cols > 0 {
+		offset, count = []uint{start, 0}, []uint{n, cols}
+	}
+	return writeHyperslab(dset, offset, count, data)
+}
+
+// Fonction auxiliaire pour écrire data dans le bloc count du dataset débutant à offset
+// (sélection hyperslab) ; la pile d'erreurs HDF5 d'un échec d'écriture est relevée
+func writeHyperslab(dset *hdf5.Dataset, offset, count []uint, data interface{}) error {
+	filespace := dset.Space()
+	if filespace == nil {
+		return fmt.Errorf("espace de données inaccessible")
+	}
+	defer filespace.Close()
+
+	if err := filespace.SelectHyperslab(offset, nil, count, nil); err != nil {
+		return err
+	}
+	memspace, err := hdf5.CreateSimpleDataspace(count, nil)
+	if err != nil {
+		return err
+	}
+	defer memspace.Close()
+
+	return hdf5.CaptureErrorStack(dset.WriteSubset(data, memspace, filespace))
+}
+
+// Fonction auxiliaire pour déterminer le nombre de lignes des blocs d'écriture d'une série
+// de rows lignes (opts.BlockRows ; toute la série si elle tient dans un bloc)
+func blockRows(rows int, opts Options) int {
+	if opts.BlockRows <= 0 || rows <= opts.BlockRows {
+		return rows
+	}
+	return opts.BlockRows
+}
+
+// Fonction auxiliaire pour aligner les blocs d'écriture sur les chunks, dont la forme est
+// conservée : un bloc compte un nombre entier de chunks, au moins un
+func alignBlockRows(block int, chunks []uint) int {
+	height := int(chunks[0])
+	return max(height, block-block%height)
+}
+
+// Hauteur minimale des chunks en mode ajout, sans opts.BlockRows
+const minAppendChunkRows = 1024
+
+// Fonction auxiliaire pour fixer, en mode ajout, une hauteur minimale de chunks
+// (opts.BlockRows, ou minAppendChunkRows) : la première série écrite ne doit pas imposer
+// sa taille aux chunks des lignes ajoutées ensuite
+func setAppendChunkRows(chunks []uint, opts Options) {
+	if !opts.Append {
+		return
+	}
+	minRows := uint(minAppendChunkRows)
+	if opts.BlockRows > 0 {
+		minRows = uint(opts.BlockRows)
+	}
+	chunks[0] = max(chunks[0], minRows)
+}
+
+// Fonction pour écrire une série sous forme de tableau 1-D d'enregistrements composés :
+// l'horodatage "t" (int64, dans l'unité d'origine) suivi de la ou des valeurs (float64)
+func writeCompoundDataset(loc location, name string, entry DataEntryFloat, attrs attributeSize, opts Options) (*hdf5.Dataset, hdf5.Layout, error) {
+	rows := len(entry.V)
+	cols := len(entry.V[0])
+
+	record := compoundRecordLayout(cols)
+	dtype, err := record.datatype()
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du type composé: %w", err)
+	}
+	defer dtype.Close()
+
+	var maxDims []uint
+	if opts.Append {
+		maxDims = []uint{hdf5.S_UNLIMITED}
+	}
+	space, err := hdf5.CreateSimpleDataspace([]uint{uint(rows)}, maxDims)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de l'espace de données: %w", err)
+	}
+	defer space.Close()
+
+	// Écriture par blocs d'enregistrements des grandes séries, alignés sur les chunks
+	chunks := []uint{uint(rows)}
+	setAppendChunkRows(chunks, opts)
+	block := alignBlockRows(blockRows(rows, opts), chunks)
+
+	prop, layout, err := newDatasetPropList(rows, cols, chunks, attrs, opts)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+	defer prop.Close()
+
+	dset, err := loc.CreateDatasetWith(name, &dtype.Datatype, space, prop)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création du dataset '%s': %w", name, err)
+	}
+
+	// Encoder puis écrire les enregistrements, bloc par bloc
+	err = writeBlocks(rows, block, func(start, n int) error {
+		buf := encodeCompoundRecords(record, entry.V[start:start+n], opts)
+		return writeRowsSubset(dset, uint(start), uint(n), 0, &buf)
+	})
+	if err != nil {
+		dset.Close() // pile d'erreurs relevée par writeRowsSubset
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	return dset, layout, nil
+}
+
+// Fonction auxiliaire pour encoder des lignes en enregistrements composés ;
+// l'horodatage retrouve son unité d'origine
+func encodeCompoundRecords(record *recordLayout, rows [][]float64, opts Options) []byte {
+	buf := make([]byte, len(rows)*record.Size)
+	for i, row := range rows {
+		data := buf[i*record.Size:]
+		t := int64(math.Round(row[0] * opts.TimestampDivisor))
+		binary.NativeEndian.PutUint64(data[record.Fields[0].Offset:], uint64(t))
+		for j := 1; j < len(record.Fields) && j < len(row); j++ {
+			binary.NativeEndian.PutUint64(data[record.Fields[j].Offset:], math.Float64bits(row[j]))
+		}
+	}
+	return buf
+}
+
+// Fonction auxiliaire pour décrire l'enregistrement d'une série de cols colonnes :
+// "t" puis "value", ou "value_1" à "value_<n>" s'il y a plusieurs valeurs
+func compoundRecordLayout(cols int) *recordLayout {
+	fields := []recordField{int64Field("t")}
+	for j := 1; j < cols; j++ {
+		name := "value"
+		if cols > 2 {
+			name = fmt.Sprintf("value_%d", j)
+		}
+		fields = append(fields, float64Field(name))
+	}
+	return newRecordLayout(fields...)
+}
+
+// Fonction auxiliaire pour créer la liste de propriétés de création d'un dataset de rows×cols
+// valeurs de 8 octets portant les attributs attrs : type de stockage, chunking, compression
+// et stockage des attributs. La liste retournée doit être fermée par l'appelant.
+func newDatasetPropList(rows, cols int, chunks []uint, attrs attributeSize, opts Options) (*hdf5.PropList, hdf5.Layout, error) {
+	// Créer la propriété pour le stockage et la compression
+	prop, err := hdf5.NewPropList(hdf5.P_DATASET_CREATE)
+	if err != nil {
+		return nil, hdf5.D_LAYOUT_ERROR, stageError(StageDataset, "création de la liste de propriétés: %w", err)
+	}
+
+	// Choisir le type de stockage en fonction de la taille des données
+	layout := chooseLayout(rows, cols, attrs.headerBytes(), opts)
+	if err := prop.SetLayout(layout); err != nil {
+		err = stageError(StageDataset, "configuration du stockage '%v': %w", layout, err)
+		prop.Close()
+		return nil, hdf5.D_LAYOUT_ERROR, err
+	}
+
+	// Au-delà de la limite de stockage compact, les attributs passent en stockage dense
+	// pour ne pas alourdir l'en-tête du dataset
+	if attrs.count > maxCompactAttributes {
+		if err := prop.SetAttrPhaseChange(0, 0); err != nil {
+			err = stageError(StageDataset, "activation du stockage dense des attributs: %w", err)
+			prop.Close()
+			return nil, hdf5.D_LAYOUT_ERROR, err
+		}
+	}
+
+	// Les petits datasets (compact ou contigu) ne sont ni chunkés ni compressés
+	if layout == hdf5.D_CHUNKED {
+		if err := prop.SetChunk(chunks); err != nil {
+			err = stageError(StageDataset, "configuration du chunking: %w", err)
+			prop.Close()
+			return nil, hdf5.D_LAYOUT_ERROR, err
+		}
+
+		// Activer la compression GZIP (niveau 9 par défaut)
+		setCompression(prop, opts)
+	}
+
+	return prop, layout, nil
+}
+
+// Fonction auxiliaire pour ajouter les tables d'états des séries connues aux attributs "a"
+func addStateAttributes(entry *DataEntryFloat) {
+	if entry.A == nil {
+		entry.A = make(map[string]interface{})
+	}
+
+	// Ajout d'atributs pour le dataset s3p.activity
+	if strings.Contains(entry.C, "s3p.activity") {
+		entry.A["state_R"] = uint8(1)
+		entry.A["state_r"] = uint8(0)
+		entry.A["state_D"] = uint8(7)
+		entry.A["state_d"] = uint8(6)
+		entry.A["state_W"] = uint8(5)
+		entry.A["state_w"] = uint8(4)
+		entry.A["state_A"] = uint8(3)
+		entry.A["state_a"] = uint8(2)
+	}
+
+	// Ajout d'attributs pour le dataset s3p.cruiseControlActive"
+	if strings.Contains(entry.C, "s3p.cruiseControlActive") {
+		entry.A["state_TRUE"] = uint8(1)
+		entry.A["state_OFF"] = uint8(0)
+	}
+
+	// Ajout d'attributs pour le dataset s3p.ignition
+	if strings.Contains(entry.C, "s3p.ignition") {
+		entry.A["state_ON"] = uint8(1)
+		entry.A["state_OFF"] = uint8(0)
+	}
+}
+
+// Fonction auxiliaire pour activer le filtre shuffle (opts.Shuffle) et la compression GZIP
+// (opts.Compression, 0 : aucune) d'un dataset chunké
+func setCompression(prop *hdf5.PropList, opts Options) {
+	if opts.Shuffle {
+		if err := prop.SetShuffle(); err != nil {
+			opts.logger().Warn("Le filtre shuffle n'a pas pu être activé", "event", "shuffle_unavailable", "error", err)
+		}
+	}
+	if opts.Compression == 0 {
+		return
+	}
+	if err := prop.SetDeflate(opts.Compression); err != nil {
+		opts.logger().Warn("La compression GZIP n'a pas pu être activée", "event", "compression_unavailable", "level", opts.Compression, "error", err)
+	}
+}
+
+// Seuils par défaut (en octets de données brutes) pour le choix automatique du stockage
+const (
+	compactLayoutMaxBytes    = 16 * 1024  // en dessous : stockage compact dans l'en-tête du dataset
+	contiguousLayoutMaxBytes = 256 * 1024 // en dessous : stockage contigu, sans filtre
+
+	maxCompactLayoutBytes = 64*1024 - 1024 // limite HDF5 du stockage compact, moins une marge pour l'en-tête
+)
+
+// Fonction auxiliaire pour choisir le type de stockage d'un dataset de float64.
+// Le chunking et la compression ne valent la peine que pour les gros datasets :
+// pour quelques lignes, leur surcoût dépasse la taille des données.
+// Le stockage compact est limité à 64 Kio par HDF5, pour les données et les attributs
+// stockés dans l'en-tête du dataset (headerBytes octets) : au-delà, le dataset est
+// contigu. Seuls les datasets chunkés sont extensibles.
+func chooseLayout(rows, cols, headerBytes int, opts Options) hdf5.Layout {
+	size := rows * cols * 8
+	switch {
+	case opts.Append:
+		return hdf5.D_CHUNKED
+	case size <= opts.CompactMaxBytes && size+headerBytes <= maxCompactLayoutBytes:
+		return hdf5.D_COMPACT
+	case size <= opts.ContiguousMaxBytes:
+		return hdf5.D_CONTIGUOUS
+	default:
+		return hdf5.D_CHUNKED
+	}
+}
@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Les blocs d'écriture comptent un nombre entier de chunks, dont la forme est conservée
+func TestAlignBlockRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		block  int
+		chunks []uint
+		want   int
+	}{
+		{name: "multiple exact", block: 1000, chunks: []uint{100, 3}, want: 1000},
+		{name: "arrondi inférieur", block: 1050, chunks: []uint{100, 3}, want: 1000},
+		{name: "bloc plus petit qu'un chunk", block: 50, chunks: []uint{100, 1}, want: 100},
+		{name: "chunks d'une ligne", block: 37, chunks: []uint{1, 4}, want: 37},
+		{name: "série entière", block: 500, chunks: []uint{500}, want: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := append([]uint(nil), tt.chunks...)
+			if got := alignBlockRows(tt.block, chunks); got != tt.want {
+				t.Errorf("alignBlockRows(%d, %v) = %d, attendu %d", tt.block, tt.chunks, got, tt.want)
+			}
+			if !reflect.DeepEqual(chunks, tt.chunks) {
+				t.Errorf("forme des chunks modifiée: %v, attendu %v", chunks, tt.chunks)
+			}
+		})
+	}
+}
+
+// Taille des blocs d'écriture : opts.BlockRows, ou toute la série
+func TestBlockRows(t *testing.T) {
+	tests := []struct {
+		rows, blockRows, want int
+	}{
+		{rows: 10, blockRows: 0, want: 10},
+		{rows: 10, blockRows: 100, want: 10},
+		{rows: 1000, blockRows: 100, want: 100},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.BlockRows = tt.blockRows
+		if got := blockRows(tt.rows, opts); got != tt.want {
+			t.Errorf("blockRows(%d) avec BlockRows=%d: %d, attendu %d", tt.rows, tt.blockRows, got, tt.want)
+		}
+	}
+}
+
+// Choix du stockage : compact tant que les données et les attributs de l'en-tête tiennent
+// dans la limite de HDF5, puis contigu, puis chunké
+func TestChooseLayout(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows, cols  int
+		headerBytes int
+		opts        func(*Options)
+		want        hdf5.Layout
+	}{
+		{name: "petit dataset", rows: 10, cols: 2, want: hdf5.D_COMPACT},
+		{name: "petit dataset, attributs volumineux", rows: 1000, cols: 2, headerBytes: 50000, want: hdf5.D_CONTIGUOUS},
+		{name: "petit dataset, quelques attributs", rows: 1000, cols: 2, headerBytes: 2000, want: hdf5.D_COMPACT},
+		{name: "seuil compact dépassé", rows: 2000, cols: 2, want: hdf5.D_CONTIGUOUS},
+		{
+			name: "seuil compact au-delà de la limite de HDF5", rows: 5000, cols: 2,
+			opts: func(opts *Options) { opts.CompactMaxBytes = 100000; opts.ContiguousMaxBytes = 200000 },
+			want: hdf5.D_CONTIGUOUS,
+		},
+		{name: "seuil contigu dépassé", rows: 20000, cols: 2, want: hdf5.D_CHUNKED},
+		{name: "mode ajout", rows: 1, cols: 2, opts: func(opts *Options) { opts.Append = true }, want: hdf5.D_CHUNKED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			if got := chooseLayout(tt.rows, tt.cols, tt.headerBytes, opts); got != tt.want {
+				t.Errorf("chooseLayout(%d, %d, %d) = %v, attendu %v", tt.rows, tt.cols, tt.headerBytes, got, tt.want)
+			}
+		})
+	}
+}
+
+// Taille des attributs dans l'en-tête d'un objet : valeurs dans leur représentation HDF5,
+// aucune en stockage dense
+func TestAttributeSize(t *testing.T) {
+	values := []struct {
+		value interface{}
+		want  int
+	}{
+		{value: int64(1), want: 8},
+		{value: 1, want: 8},
+		{value: uint8(1), want: 1},
+		{value: true, want: 1},
+		{value: 1.5, want: 8},
+		{value: []float64{1, 2, 3}, want: 24},
+		{value: "texte", want: varStringBytes},
+		{value: []string{"a", "b"}, want: 2 * varStringBytes},
+		{value: fixedString("abc"), want: 4},
+	}
+	for _, tt := range values {
+		if got := attributeValueBytes(tt.value); got != tt.want {
+			t.Errorf("attributeValueBytes(%#v) = %d, attendu %d", tt.value, got, tt.want)
+		}
+	}
+
+	attrs := attributeSize{}.add("la", uint8(1)).addBytes("meta", 100)
+	if want := 2*attributeMessageBytes + len("la") + 1 + len("meta") + 100; attrs.count != 2 || attrs.headerBytes() != want {
+		t.Errorf("attributs %+v, attendu 2 attributs de %d octets", attrs, want)
+	}
+	for i := 0; i < maxCompactAttributes; i++ {
+		attrs = attrs.add("x", int64(i))
+	}
+	if attrs.headerBytes() != 0 {
+		t.Errorf("%d attributs: %d octets dans l'en-tête, attendu 0 (stockage dense)", attrs.count, attrs.headerBytes())
+	}
+}
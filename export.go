@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"gonum.org/v1/hdf5"
+
+	"hdf5_test2/converter"
+)
+
+// Commande export : reconstruit le JSON d'origine ([][]DataEntryRaw) à partir d'un fichier
+// HDF5 produit par le convertisseur (formats matrix, compound et mat).
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	logOpts := addLogFlags(flags)
+	indent := flags.Bool("indent", false, "indenter le JSON produit")
+	cfgFlags := addConfigFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("Usage: ./hdf5_test2 export [options] input.h5 output.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := logOpts.setup(); err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	inputFile := flags.Arg(0)
+	outputFile := flags.Arg(1)
+
+	// Le prétraitement à annuler est celui de la configuration de la conversion
+	cfg, err := cfgFlags.config()
+	if err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	f, err := hdf5.OpenFile(inputFile, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		fatal("hdf5_open_error", "Erreur lors de l'ouverture du fichier HDF5", "error", err)
+	}
+	defer f.Close()
+
+	datasets, count, err := converter.ExportFile(f, cfg.options())
+	if errors.Is(err, converter.ErrUnsupportedFormat) {
+		fatal("unsupported_format", "Format non pris en charge par l'export", "error", err)
+	}
+	if err != nil {
+		fatal("hdf5_read_error", "Erreur lors de la lecture du fichier HDF5", "error", err)
+	}
+
+	var jsonData []byte
+	if *indent {
+		jsonData, err = json.MarshalIndent(datasets, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(datasets)
+	}
+	if err != nil {
+		fatal("json_encode_error", "Erreur lors de l'encodage du JSON", "error", err)
+	}
+	if err := os.WriteFile(outputFile, jsonData, 0644); err != nil {
+		fatal("output_write_error", "Erreur lors de l'écriture du fichier JSON", "error", err)
+	}
+
+	slog.Info("Export réussi", "event", "export_done", "output", outputFile, "entries", count)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+
+	"hdf5_test2/converter"
+)
+
+// Commande inspect : affiche l'arborescence d'un fichier produit par le convertisseur,
+// avec pour chaque dataset sa forme, son type, son stockage et ses attributs
+func runInspect(args []string) {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	logOpts := addLogFlags(flags)
+	asJSON := flags.Bool("json", false, "sortie JSON au lieu du texte")
+	flags.Usage = func() {
+		fmt.Println("Usage: ./hdf5_test2 inspect [options] input.h5")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := logOpts.setup(); err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	f, err := hdf5.OpenFile(flags.Arg(0), hdf5.F_ACC_RDONLY)
+	if err != nil {
+		fatal("hdf5_open_error", "Erreur lors de l'ouverture du fichier HDF5", "error", err)
+	}
+	defer f.Close()
+
+	root, err := converter.InspectFile(f)
+	if err != nil {
+		fatal("hdf5_read_error", "Erreur lors de la lecture du fichier HDF5", "error", err)
+	}
+
+	if *asJSON {
+		jsonData, err := json.MarshalIndent(root, "", "  ")
+		if err != nil {
+			fatal("json_encode_error", "Erreur lors de l'encodage du JSON", "error", err)
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+	printInspectObject(root)
+}
+
+// Fonction pour afficher la description d'un objet et de ses enfants en texte
+func printInspectObject(obj *converter.InspectObject) {
+	if obj.Kind == "group" {
+		fmt.Printf("%s (groupe)\n", obj.Path)
+	} else {
+		fmt.Printf("%s (dataset)\n", obj.Path)
+		fmt.Printf("  forme: %s, type: %s\n", formatShape(obj.Shape), obj.Dtype)
+		storage := obj.Layout
+		if len(obj.Chunks) > 0 {
+			storage += ", chunks " + formatShape(obj.Chunks)
+		}
+		if len(obj.Filters) > 0 {
+			storage += ", filtres " + strings.Join(obj.Filters, " ")
+		}
+		fmt.Printf("  stockage: %s\n", storage)
+		fmt.Printf("  taille: %s stockés / %s logiques", formatBytes(obj.StoredBytes), formatBytes(obj.LogicalBytes))
+		if obj.LogicalBytes > 0 {
+			fmt.Printf(" (%.0f %%)", 100*float64(obj.StoredBytes)/float64(obj.LogicalBytes))
+		}
+		fmt.Println()
+	}
+
+	if len(obj.Attributes) > 0 {
+		fmt.Println("  attributs:")
+		for _, attr := range obj.Attributes {
+			value, _ := json.Marshal(attr.Value)
+			fmt.Printf("    %s (%s) = %s\n", attr.Name, attr.Dtype, value)
+		}
+	}
+	if len(obj.States) > 0 {
+		states := make([]string, len(obj.States))
+		for i, state := range obj.States {
+			states[i] = fmt.Sprintf("%d=%s", state.Code, state.Label)
+		}
+		fmt.Printf("  états: %s\n", strings.Join(states, ", "))
+	}
+
+	for _, child := range obj.Children {
+		printInspectObject(child)
+	}
+}
+
+// Fonction auxiliaire pour afficher une forme (ex. "120 x 2", "scalaire")
+func formatShape(shape []uint) string {
+	if len(shape) == 0 {
+		return "scalaire"
+	}
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = fmt.Sprint(d)
+	}
+	return strings.Join(dims, " x ")
+}
+
+// Fonction auxiliaire pour afficher une taille en octets (ex. "1.5 Kio")
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d o", n)
+	}
+	value, exp := float64(n), 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %s", value, []string{"o", "Kio", "Mio", "Gio"}[exp])
+}
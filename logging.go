@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"hdf5_test2/converter"
+)
+
+// Formats du journal (option -log-format)
+const (
+	logFormatText = "text" // lignes clé=valeur
+	logFormatJSON = "json" // un objet JSON par ligne
+)
+
+// Options du journal, communes à toutes les commandes
+type logFlags struct {
+	format  *string
+	quiet   *bool
+	verbose *bool
+}
+
+// Fonction pour déclarer les options du journal sur le jeu d'options d'une commande
+func addLogFlags(flags *flag.FlagSet) *logFlags {
+	return &logFlags{
+		format:  flags.String("log-format", logFormatText, "format du journal sur la sortie d'erreur: text ou json"),
+		quiet:   flags.Bool("quiet", false, "n'afficher que les avertissements et les erreurs"),
+		verbose: flags.Bool("verbose", false, "afficher aussi le détail de chaque série (niveau debug)"),
+	}
+}
+
+// Fonction pour installer le journal décrit par les options comme journal par défaut ;
+// les messages du paquet log standard y sont aussi redirigés
+func (lf *logFlags) setup() error {
+	if *lf.quiet && *lf.verbose {
+		return fmt.Errorf("les options -quiet et -verbose sont incompatibles")
+	}
+	level := slog.LevelInfo
+	switch {
+	case *lf.quiet:
+		level = slog.LevelWarn
+	case *lf.verbose:
+		level = slog.LevelDebug
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch *lf.format {
+	case logFormatText:
+		handler = slog.NewTextHandler(stderr, handlerOpts)
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(stderr, handlerOpts)
+	default:
+		return fmt.Errorf("format de journal inconnu: '%s' (attendu text ou json)", *lf.format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Fonction pour journaliser une erreur et terminer le programme. Comme tous les messages
+// du journal, l'erreur porte un attribut "event", identifiant stable destiné au filtrage
+// et à l'analyse du journal ; le texte du message peut évoluer.
+func fatal(event, msg string, args ...any) {
+	stderr.dropLine()
+	slog.Error(msg, append([]any{"event", event}, args...)...)
+	os.Exit(exitFailure)
+}
+
+// Fonction auxiliaire pour décrire une erreur en champs du journal, avec le contexte de
+// la série (canal, position dans le JSON, valeur, étape) pour une erreur de série
+func errorAttrs(err error) []any {
+	var entryErr *converter.EntryError
+	if !errors.As(err, &entryErr) {
+		return []any{"error", err}
+	}
+	return entryAttrs(entryErr.Channel, entryErr.Path, entryErr.OuterIndex, entryErr.EntryIndex,
+		entryErr.Row, entryErr.Col, entryErr.Stage, entryErr.Err.Error())
+}
+
+// Fonction auxiliaire pour décrire une série en échec en champs du journal
+func failureAttrs(failure converter.Failure) []any {
+	attrs := entryAttrs(failure.Channel, failure.Path, failure.OuterIndex, failure.EntryIndex,
+		failure.Row, failure.Col, failure.Stage, failure.Error)
+	if failure.CleanupError != "" {
+		attrs = append(attrs, "cleanup_error", failure.CleanupError)
+	}
+	return attrs
+}
+
+// Fonction auxiliaire pour les champs communs aux erreurs de série ; les positions
+// inconnues (-1) sont omises
+func entryAttrs(channel, path string, outerIndex, entryIndex, row, col int, stage, msg string) []any {
+	attrs := []any{"channel", channel, "path", path}
+	if outerIndex >= 0 && entryIndex >= 0 {
+		attrs = append(attrs, "outer_index", outerIndex, "entry_index", entryIndex)
+	}
+	if row >= 0 && col >= 0 {
+		attrs = append(attrs, "row", row, "col", col)
+	}
+	return append(attrs, "stage", stage, "error", msg)
+}
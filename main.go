@@ -1,401 +1,340 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"strconv"
-	"strings"
 
 	"gonum.org/v1/hdf5"
+
+	"hdf5_test2/converter"
+)
+
+// Commandes de la ligne de commande
+var commands = map[string]func(args []string){
+	"convert":  runConvert,
+	"validate": runValidate,
+	"inspect":  runInspect,
+	"export":   runExport,
+	"verify":   runVerify,
+}
+
+// Codes de sortie de la commande convert (2 est réservé aux erreurs d'options du
+// paquet flag)
+const (
+	exitSuccess = 0 // toutes les séries ont été écrites
+	exitFailure = 1 // conversion interrompue, ou aucune série écrite
+	exitPartial = 3 // des séries en échec ont été ignorées (mode keep-going)
+)
+
+// Statuts du rapport d'exécution
+const (
+	statusSuccess = "success"
+	statusPartial = "partial"
+	statusFailure = "failure"
 )
 
-// Structure pour représenter une entrée de données avant traitement de conversion en float64
-type DataEntryRaw struct {
-	C  string            `json:"c"`
-	L  map[string]string `json:"l"`
-	A  map[string]uint8  `json:"a"`
-	La uint8             `json:"la"`
-	V  [][]interface{}   `json:"v"`
+// Rapport d'exécution de la conversion, écrit en JSON avec l'option -report
+type runReport struct {
+	Input    string                      `json:"input"`
+	Output   string                      `json:"output"`
+	Status   string                      `json:"status"` // statusSuccess, statusPartial ou statusFailure
+	Entries  int                         `json:"entries"`
+	Failed   int                         `json:"failed"`
+	Error    string                      `json:"error,omitempty"` // erreur ayant interrompu la conversion
+	Failures []converter.Failure         `json:"failures"`
+	Warnings []converter.ChannelWarnings `json:"warnings,omitempty"` // valeurs non converties
 }
 
-// Structure pour représenter une entrée de données après traitement de conversion en float64
-type DataEntryFloat struct {
-	C  string            `json:"c"`
-	L  map[string]string `json:"l"`
-	A  map[string]uint8  `json:"a"`
-	La uint8             `json:"la"`
-	V  [][]float64       `json:"v"`
+// Nombre maximal de séries détaillées dans le résumé des avertissements
+const maxWarningChannels = 10
+
+// Fonction pour afficher l'aide générale
+func printUsage() {
+	fmt.Println("Usage: ./hdf5_test2 <commande> [options] arguments")
+	fmt.Println()
+	fmt.Println("Commandes:")
+	fmt.Println("  convert [options] input.json output.h5   conversion JSON vers HDF5 (commande par défaut)")
+	fmt.Println("  validate [options] input.json            vérification de la configuration et du JSON, sans écriture")
+	fmt.Println("  inspect [options] input.h5               contenu d'un fichier HDF5")
+	fmt.Println("  export [options] input.h5 output.json    reconstruction du JSON source")
+	fmt.Println("  verify [options] input.json output.h5    comparaison d'un fichier HDF5 à son JSON source")
+	fmt.Println()
+	fmt.Println("Options communes : -config fichier.json, -profile archive|analysis, et une option par")
+	fmt.Println("clé du fichier de configuration ; voir ./hdf5_test2 <commande> -h")
+	fmt.Println("Journal (sortie d'erreur) : -log-format text|json, -quiet, -verbose")
 }
 
 func main() {
 
+	// Sans nom de commande, les arguments sont ceux de la conversion (compatibilité
+	// avec l'ancienne ligne de commande)
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			run(os.Args[2:])
+			return
+		}
+		switch os.Args[1] {
+		case "help", "-h", "-help", "--help":
+			printUsage()
+			return
+		}
+	}
+	runConvert(os.Args[1:])
+}
+
+// Commande convert : conversion d'un fichier JSON en fichier HDF5
+func runConvert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	logOpts := addLogFlags(flags)
+	cfgFlags := addConfigFlags(flags)
+	reportFile := flags.String("report", "", "fichier JSON recevant le rapport d'exécution (séries en échec, étape, pile d'erreurs HDF5)")
+	debugObjects := flags.Bool("debug-objects", false, "afficher en fin de conversion le nombre d'objets HDF5 encore ouverts (diagnostic des fuites)")
+	progressMode := flags.String("progress", progressAuto, "affichage de l'avancement sur la sortie d'erreur: auto (si terminal), tty, json (événements NDJSON) ou none")
+	flags.Usage = func() {
+		fmt.Println("Usage: ./hdf5_test2 [convert] [options] input.json output.h5")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := logOpts.setup(); err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
 	// Vérifier les arguments de la ligne de commande
-	if len(os.Args) != 3 {
-		fmt.Println("Usage: ./hdf5_test2 input.json output.h5")
+	if flags.NArg() != 2 {
+		flags.Usage()
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
-	outputFile := os.Args[2]
+	inputFile := flags.Arg(0)
+	outputFile := flags.Arg(1)
 
-	// Lire le fichier JSON
-	jsonData, err := os.ReadFile(inputFile)
+	cfg, err := cfgFlags.config()
 	if err != nil {
-		log.Fatalf("Erreur lors de la lecture du fichier JSON: %v", err)
+		fatal("config_error", "Erreur de configuration", "error", err)
 	}
+	opts := cfg.options()
 
-	// Décodage du JSON brut
-	var rawdatasets [][]DataEntryRaw
-	err = json.Unmarshal(jsonData, &rawdatasets)
+	// Lire le fichier JSON
+	input, err := os.Open(inputFile)
 	if err != nil {
-		log.Fatalf("Erreur lors du décodage du JSON: %v", err)
+		fatal("input_open_error", "Erreur lors de la lecture du fichier JSON", "error", err)
 	}
+	defer input.Close()
 
-	// Prétraiter les données JSON pour convertir toutes les valeurs V en float64
-	datasets := preprocessJsonData(rawdatasets)
+	// Avancement, rapporté aux octets lus du fichier JSON
+	var inputSize int64
+	if info, err := input.Stat(); err == nil {
+		inputSize = info.Size()
+	}
+	progress, err := newProgressReporter(*progressMode, inputSize)
+	if err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+	var source io.Reader = input
+	if progress != nil {
+		opts.Progress = progress.update
+		source = progress.reader(input)
+	}
 
-	// Créer un fichier HDF5
-	f, err := hdf5.CreateFile(outputFile, hdf5.F_ACC_TRUNC)
+	// Créer un fichier HDF5 (avec le bloc utilisateur de l'en-tête MATLAB en mode mat),
+	// ou ouvrir le fichier existant en mode ajout
+	var f *hdf5.File
+	appending := false
+	if opts.Append {
+		_, statErr := os.Stat(outputFile)
+		appending = statErr == nil
+	}
+	switch {
+	case appending:
+		f, err = hdf5.OpenFile(outputFile, hdf5.F_ACC_RDWR)
+	case opts.Format == converter.FormatMat:
+		f, err = converter.CreateMatFile(outputFile)
+	default:
+		f, err = hdf5.CreateFile(outputFile, hdf5.F_ACC_TRUNC)
+	}
 	if err != nil {
-		log.Fatalf("Erreur lors de la création du fichier HDF5: %v", err)
+		fatal("hdf5_create_error", "Erreur lors de la création du fichier HDF5", "error", err)
 	}
 	defer f.Close()
 
-	// Parcourir tous les datasets
-	for _, dataset := range datasets {
-		// Créer un groupe pour chaque dataset
-		/*groupName := fmt.Sprintf("dataset_%d", datasetIndex)
-		group, err := f.CreateGroup(groupName)
-		if err != nil {
-			log.Fatalf("Erreur lors de la création du groupe HDF5: %v", err)
+	// Attributs globaux du mode CF
+	if opts.Format == converter.FormatCF {
+		if err := converter.WriteCFGlobalAttributes(f); err != nil {
+			abandonOutput(f, outputFile, !appending)
+			fatal("cf_attributes_error", "Erreur lors de l'ajout des attributs globaux CF", "error", err)
 		}
-		defer group.Close()*/
-
-		// Garder une trace des noms de datasets déjà utilisés
-		datasetNames := make(map[string]int)
-
-		// Parcourir toutes les entrées dans le dataset
-		for _, entry := range dataset {
-			// Vérifier qu'il y a des données à stocker
-			if len(entry.V) == 0 {
-				continue // Passer à l'entrée suivante si aucune donnée
-			}
-
-			// Vérifier si le nom existe déjà et générer un nom unique
-			baseName := entry.C
-			count, exists := datasetNames[baseName]
-
-			var uniqueName string
-			if exists {
-				// Incrémenter le compteur et l'utiliser comme suffixe
-				count++
-				datasetNames[baseName] = count
-				uniqueName = fmt.Sprintf("%s_%d", baseName, count)
-			} else {
-				// Premier dataset avec ce nom
-				datasetNames[baseName] = 0
-				uniqueName = baseName
-			}
-
-			// Déterminer les dimensions du dataset
-			rows := len(entry.V)
-			cols := len(entry.V[0])
-
-			// Créer un espace pour le dataset
-			dims := []uint{uint(rows), uint(cols)}
-			space, err := hdf5.CreateSimpleDataspace(dims, nil)
-			if err != nil {
-				log.Fatalf("Erreur lors de la création de l'espace de données: %v", err)
-			}
-			defer space.Close()
-
-			// Créer la propriété pour la compression
-			prop, err := hdf5.NewPropList(hdf5.P_DATASET_CREATE)
-			if err != nil {
-				log.Fatalf("Erreur lors de la création de la liste de propriétés: %v", err)
-			}
-			defer prop.Close()
-
-			// Configuer le chunking par colonne
-			chunks := []uint{uint(rows), 1}
-
-			// Configurer le chunking par ligne
-			// chunks := []uint{1, uint(cols)}
-
-			// Configurer le chunking sur la base de la taille de la matrice
-			//chunks := []uint{uint(rows), uint(cols)}
-			//if err := prop.SetChunk(chunks); err != nil {
-			//log.Fatalf("Erreur lors de la configuration du chunking: %v", err)
-			//}
-
-			// Configurer le chunking de manière optimale
-			/*var chunks []uint
-			if rows > cols {
-				chunks = []uint{uint(min(rows, 100)), uint(cols)} // chunk par blocs de lignes
-			} else {
-				chunks = []uint{uint(rows), uint(min(cols, 100))} // chunk par blocs de colonnes
-			}*/
-
-			if err := prop.SetChunk(chunks); err != nil {
-				log.Fatalf("Erreur lors de la configuration du chunking: %v", err)
-			}
-
-			// Activer la compression GZIP (niveau 9)
-			if err := prop.SetDeflate(9); err != nil {
-				log.Printf("Avertissement: La compression GZIP n'a pas pu être activée: %v.", err)
-			}
-
-			// Créer un dataset directement avec le nom "c" de type float64
-			dset, err := f.CreateDatasetWith(uniqueName, hdf5.T_NATIVE_DOUBLE, space, prop)
-			if err != nil {
-				log.Fatalf("Erreur lors de la création du dataset '%s': %v", entry.C, err)
-			}
-			defer dset.Close()
-
-			// Pour garder une trace de l'association avec le nom original
-			if err == nil && uniqueName != baseName {
-				if err := addStringAttribute(dset, "original_name", baseName); err != nil {
-					log.Printf("Erreur lors de l'ajout de l'attribut 'original_name': %v", err)
-				}
-			}
-
-			// Ajout d'atributs pour le dataset s3p.activity
-			if strings.Contains(entry.C, "s3p.activity") {
-				entry.A["state_R"] = 1
-				entry.A["state_r"] = 0
-				entry.A["state_D"] = 7
-				entry.A["state_d"] = 6
-				entry.A["state_W"] = 5
-				entry.A["state_w"] = 4
-				entry.A["state_A"] = 3
-				entry.A["state_a"] = 2
-			}
-
-			// Ajout d'attributs pour le dataset s3p.cruiseControlActive"
-			if strings.Contains(entry.C, "s3p.cruiseControlActive") {
-				entry.A["state_TRUE"] = 1
-				entry.A["state_OFF"] = 0
-			}
-
-			// Ajout d'attributs pour le dataset s3p.ignition
-			if strings.Contains(entry.C, "s3p.ignition") {
-				entry.A["state_ON"] = 1
-				entry.A["state_OFF"] = 0
-			}
-
-			// Pour "l" (convertir la map en attributs)
-			for key, value := range entry.L {
-				// Convertir la valeur en string pour simplification
-				strValue := fmt.Sprintf("%v", value)
-				if err := addStringAttribute(dset, "l_"+key, strValue); err != nil {
-					log.Fatalf("Erreur lors de l'ajout de l'attribut 'l_%s': %v", key, err)
-				}
-			}
-
-			// Pour "a" (attributs)
-			for key, value := range entry.A {
-				//strValue := fmt.Sprintf("%v", value)
-				if err := addIntAttribute(dset, "a_"+key, value); err != nil {
-					log.Fatalf("Erreur lors de l'ajout de l'attribut 'a_%s': %v", key, err)
-				}
-			}
-
-			// Pour "la"
-			if err := addIntAttribute(dset, "la", uint8(entry.La)); err != nil {
-				log.Fatalf("Erreur lors de l'ajout de l'attribut 'la': %v", err)
-			}
-
-			// Convertir les données 2D en format plat pour HDF5
-			flatData := make([]float64, rows*cols)
-			for i := 0; i < rows; i++ {
-				for j := 0; j < cols; j++ {
-					flatData[i*cols+j] = entry.V[i][j]
-				}
-			}
-
-			// Écrire les données
-			err = dset.Write(&flatData)
-			if err != nil {
-				log.Fatalf("Erreur lors de l'écriture des données: %v", err)
-			}
+	}
 
+	// Attributs PyTables du groupe racine en mode pandas
+	if opts.Format == converter.FormatPandas {
+		if err := converter.WritePandasGlobalAttributes(f); err != nil {
+			abandonOutput(f, outputFile, !appending)
+			fatal("pytables_attributes_error", "Erreur lors de l'ajout des attributs globaux PyTables", "error", err)
 		}
 	}
 
-	fmt.Printf("Conversion réussie. Fichier HDF5 créé: %s\n", outputFile)
-}
+	report, err := converter.Convert(context.Background(), source, f, opts)
+	if *debugObjects {
+		printOpenObjects(f)
+	}
 
-// Fonction auxiliaire pour ajouter un attribut de type string
-func addStringAttribute(obj interface{}, name, value string) error {
-	// Créer un type de données pour la chaîne
-	dtype := hdf5.T_GO_STRING
+	// L'en-tête MATLAB s'écrit dans le bloc utilisateur, une fois le fichier HDF5 fermé
+	if err == nil && opts.Format == converter.FormatMat {
+		err = finishMatFile(f, outputFile)
+	}
 
-	// Créer l'attribut
-	dspace, err := hdf5.CreateSimpleDataspace([]uint{1}, nil)
-	if err != nil {
-		return err
+	run := runReport{
+		Input:    inputFile,
+		Output:   outputFile,
+		Status:   statusSuccess,
+		Entries:  report.Entries,
+		Failed:   len(report.Failures),
+		Failures: report.Failures,
+		Warnings: report.ChannelWarnings,
+	}
+	switch {
+	case err != nil:
+		run.Status = statusFailure
+		run.Error = err.Error()
+	case run.Failed > 0 && run.Entries == 0:
+		run.Status = statusFailure
+	case run.Failed > 0:
+		run.Status = statusPartial
+	}
+	if run.Failures == nil {
+		run.Failures = []converter.Failure{}
+	}
+	if progress != nil {
+		progress.finish(run.Status)
+	}
+	if *reportFile != "" {
+		if err := writeRunReport(*reportFile, run); err != nil {
+			slog.Error("Erreur lors de l'écriture du rapport d'exécution", "event", "report_write_error", "path", *reportFile, "error", err)
+		}
 	}
 
-	var attr *hdf5.Attribute
+	printWarningSummary(report.ChannelWarnings)
+	if err != nil {
+		// Les séries déjà écrites restent lisibles dans le fichier fermé
+		abandonOutput(f, outputFile, false)
+		fatal("convert_error", "Erreur lors de la conversion", errorAttrs(err)...)
+	}
+	for _, failure := range report.Failures {
+		slog.Warn("Série ignorée", append([]any{"event", "entry_failed"}, failureAttrs(failure)...)...)
+	}
 
-	// Vérifier le type de l'objet
-	switch o := obj.(type) {
-	case *hdf5.Group:
-		attr, err = o.CreateAttribute(name, dtype, dspace)
-	case *hdf5.Dataset:
-		attr, err = o.CreateAttribute(name, dtype, dspace)
+	switch {
+	case run.Status == statusFailure:
+		slog.Error("Échec de la conversion: aucune série écrite", "event", "convert_failed", "output", outputFile, "failed", run.Failed)
+	case appending:
+		slog.Info("Ajout réussi", "event", "append_done", "output", outputFile, "extended", report.Extended,
+			"added_rows", report.AddedRows, "skipped_rows", report.SkippedRows)
 	default:
-		return fmt.Errorf("type d'objet non pris en charge pour les attributs")
+		slog.Info("Conversion réussie", "event", "convert_done", "output", outputFile, "entries", report.Entries)
 	}
-
-	if err != nil {
-		return err
+	if run.Status == statusPartial {
+		slog.Warn("Conversion partielle: séries en échec ignorées", "event", "convert_partial", "entries", run.Entries, "failed", run.Failed)
 	}
-	defer attr.Close()
+	printLayoutSummary(report.Layouts)
+
+	// Le fichier doit être fermé avant de quitter avec un code d'erreur
+	switch run.Status {
+	case statusFailure:
+		f.Close()
+		os.Exit(exitFailure)
+	case statusPartial:
+		f.Close()
+		os.Exit(exitPartial)
+	}
+}
 
-	// Écrire la valeur
-	return attr.Write(&value, dtype)
+// Fonction auxiliaire pour fermer le fichier de sortie avant de quitter sur une erreur
+// (fatal ne revient pas et les defer ne sont pas exécutés) ; avec remove, le fichier
+// créé par cette exécution est supprimé
+func abandonOutput(f *hdf5.File, outputFile string, remove bool) {
+	if err := f.Close(); err != nil {
+		slog.Error("Erreur lors de la fermeture du fichier HDF5", "event", "hdf5_close_error", "output", outputFile, "error", err)
+	}
+	if !remove {
+		return
+	}
+	if err := os.Remove(outputFile); err != nil {
+		slog.Error("Erreur lors de la suppression du fichier incomplet", "event", "output_remove_error", "output", outputFile, "error", err)
+	}
 }
 
-// Fonction auxiliaire pour ajouter un attribut de type int64
-func addIntAttribute(obj interface{}, name string, value uint8) error {
-	// Créer l'attribut
-	dspace, err := hdf5.CreateSimpleDataspace([]uint{1}, nil)
+// Fonction auxiliaire pour afficher les objets HDF5 encore ouverts dans le fichier ; après
+// la conversion, seul le fichier lui-même devrait l'être
+func printOpenObjects(f *hdf5.File) {
+	counts, err := converter.CountOpenObjects(f)
 	if err != nil {
-		return err
+		slog.Error("Erreur lors du comptage des objets HDF5 ouverts", "event", "open_objects_error", "error", err)
+		return
 	}
+	slog.Info("Objets HDF5 ouverts", "event", "open_objects", "files", counts.Files, "datasets", counts.Datasets,
+		"groups", counts.Groups, "datatypes", counts.Datatypes, "attributes", counts.Attributes)
+	if n := counts.Leaked(); n > 0 {
+		slog.Warn("Objets HDF5 non fermés après la conversion", "event", "open_objects_leaked", "leaked", n)
+	}
+}
 
-	var attr *hdf5.Attribute
-
-	// Vérifier le type de l'objet
-	switch o := obj.(type) {
-	case *hdf5.Group:
-		attr, err = o.CreateAttribute(name, hdf5.T_NATIVE_INT8, dspace)
-	case *hdf5.Dataset:
-		attr, err = o.CreateAttribute(name, hdf5.T_NATIVE_INT8, dspace)
-	default:
-		return fmt.Errorf("type d'objet non pris en charge pour les attributs")
+// Fonction pour terminer un MAT-file : fermeture du fichier HDF5, puis écriture de
+// l'en-tête MATLAB
+func finishMatFile(f *hdf5.File, outputFile string) error {
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("fermeture du fichier HDF5: %w", err)
+	}
+	if err := converter.WriteMatHeader(outputFile); err != nil {
+		return fmt.Errorf("écriture de l'en-tête MATLAB: %w", err)
 	}
+	return nil
+}
 
+// Fonction pour écrire le rapport d'exécution en JSON
+func writeRunReport(path string, run runReport) error {
+	data, err := json.MarshalIndent(run, "", "  ")
 	if err != nil {
 		return err
 	}
-	defer attr.Close()
-
-	// Écrire la valeur
-	return attr.Write(&value, hdf5.T_NATIVE_INT8)
+	return os.WriteFile(path, append(data, '\n'), 0644)
 }
 
-// Fonction auxiliaire pour convertir les différents types de données en float64
-func convertToFloat64(val interface{}, i, j int) float64 {
-	switch v := val.(type) {
-	case float64:
-		return v
-	case bool:
-		if v {
-			return 1.0
+// Fonction auxiliaire pour afficher le résumé des valeurs non converties, par série et
+// par type, avec les premiers exemples
+func printWarningSummary(warnings []converter.ChannelWarnings) {
+	if len(warnings) == 0 {
+		return
+	}
+	total := 0
+	for _, channel := range warnings {
+		for _, count := range channel.Counts {
+			total += count.Count
+		}
+	}
+	slog.Warn("Valeurs non converties, remplacées par 0.0", "event", "unconverted_values", "values", total, "channels", len(warnings))
+	for i, channel := range warnings {
+		if i == maxWarningChannels {
+			slog.Warn("Valeurs non converties: séries non détaillées", "event", "unconverted_values_truncated", "channels", len(warnings)-i)
+			break
 		}
-		return 0.0
-	/*case int64:
-	return float64(v)*/
-	case string:
-		// Tenter de convertir la chaîne en nombre si possible
-		if val == "true" || v == "TRUE" || v == "True" {
-			return 1.0
-		} else if val == "false" || v == "FALSE" || v == "False" {
-			return 0.0
-			// S3P.Activity
-		} else if val == "R" { // Début de la période de repos "rest"
-			return 1
-		} else if val == "r" { // repos
-			return 0
-		} else if val == "D" { // Début de période de conduite "driving"
-			return 7
-		} else if val == "d" { // conduite
-			return 6
-		} else if val == "W" { // Début de la période de travail "working"
-			return 5
-		} else if val == "w" { // travail
-			return 4
-		} else if val == "A" { // Début de la période de disponibilité "available"
-			return 3
-		} else if val == "a" { // disponibilité
-			return 2
-			// S3P.Ignition
-		} else if val == "ON" { // ignition on
-			return 1
-		} else if val == "OFF" { // ignition off
-			return 0
-		} else {
-			// Tentative de conversion en float
-			parsed, err := strconv.ParseFloat(v, 64)
-			if err != nil {
-				log.Printf("Avertissement: impossible de convertir la chaîne '%s' à [%d][%d] en nombre, utilisé 0.0", v, i, j)
-				return 0 // valeur par défaut
-			}
-			return parsed
+		for _, count := range channel.Counts {
+			slog.Warn("Valeurs non converties", "event", "unconverted_values_channel", "channel", channel.Channel, "path", channel.Path,
+				"kind", count.Kind, "count", count.Count, "examples", count.Examples)
 		}
-	default:
-		log.Printf("Avertissement: type non supporté à [%d][%d]: %T avec valeur %v, utilisé 0.0", i, j, val, val)
-		return 0 // valeur par défaut
 	}
 }
 
-// Fonction pour pré-traiter les données JSON et convertir toutes les valeurs V en float64
-func preprocessJsonData(rawDatasets [][]DataEntryRaw) [][]DataEntryFloat {
-	processedDatasets := make([][]DataEntryFloat, len(rawDatasets))
-
-	for datasetIndex, rawDataset := range rawDatasets {
-		processedDataset := make([]DataEntryFloat, len(rawDataset))
-
-		for entryIndex, rawEntry := range rawDataset {
-			// Créer une entrée avec les mêmes valeurs sauf pour V
-			processedEntry := DataEntryFloat{
-				C:  rawEntry.C,
-				L:  rawEntry.L,
-				A:  rawEntry.A,
-				La: rawEntry.La,
-			}
-
-			// Traiter la matrice V
-			if len(rawEntry.V) > 0 {
-				rows := len(rawEntry.V)
-				cols := len(rawEntry.V[0])
-
-				processedV := make([][]float64, rows)
-				for i := 0; i < rows; i++ {
-					processedV[i] = make([]float64, cols)
-					for j := 0; j < cols; j++ {
-						if j < len(rawEntry.V[i]) { // Protection contre les lignes de longueurs différentes
-							processedV[i][j] = convertToFloat64(rawEntry.V[i][j], i, j)
-						}
-					}
-				}
-
-				processedEntry.V = processedV
-				// Inverser l'ordre des lignes
-				//rows1 := len(processedEntry.V)
-				for i := 0; i < rows/2; i++ {
-					processedEntry.V[i], processedEntry.V[rows-i-1] = processedEntry.V[rows-i-1], processedEntry.V[i]
-				}
-
-				// Diviser les TS par 1000
-				for i := 0; i < rows; i++ {
-					for j := 0; j < cols; j++ {
-						if j == 0 {
-							processedEntry.V[i][j] = processedEntry.V[i][j] / 1000
-						}
-					}
-				}
-			}
-
-			processedDataset[entryIndex] = processedEntry
-		}
-
-		processedDatasets[datasetIndex] = processedDataset
+// Fonction auxiliaire pour afficher le résumé des types de stockage utilisés
+func printLayoutSummary(layoutCounts map[hdf5.Layout]int) {
+	total := 0
+	for _, n := range layoutCounts {
+		total += n
 	}
-
-	return processedDatasets
+	slog.Info("Datasets écrits", "event", "layout_summary", "datasets", total, "compact", layoutCounts[hdf5.D_COMPACT],
+		"contiguous", layoutCounts[hdf5.D_CONTIGUOUS], "chunked", layoutCounts[hdf5.D_CHUNKED])
 }
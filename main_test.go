@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Variable d'environnement portant les arguments de la commande à exécuter par le
+// binaire de test (voir runCommand)
+const commandArgsEnv = "HDF5_TEST2_COMMAND_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(commandArgsEnv); ok {
+		os.Args = append([]string{os.Args[0]}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(exitSuccess)
+	}
+	os.Exit(m.Run())
+}
+
+// Fonction auxiliaire pour exécuter une commande dans un processus séparé (les commandes
+// quittent avec os.Exit) ; retourne le code de sortie et la sortie d'erreur
+func runCommand(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), commandArgsEnv+"="+strings.Join(args, "\n"))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return exitSuccess, stderr.String()
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), stderr.String()
+	}
+	t.Fatalf("exécution de %v: %v", args, err)
+	return 0, ""
+}
+
+// Fonction auxiliaire pour écrire un fichier dans le répertoire temporaire du test
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("écriture de '%s': %v", path, err)
+	}
+	return path
+}
+
+// Une entrée en échec parmi des entrées valides : conversion partielle (code 3) en mode
+// keep-going, échec (code 1) sinon ; chaque ligne du journal porte un attribut "event"
+func TestConvertExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTempFile(t, dir, "input.json",
+		`[[{"c": "a", "v": [[1000, 1]]}, {"c": "bad", "la": 300, "v": [[1000, 2]]}, {"c": "b", "v": [[1000, 3]]}]]`)
+
+	tests := []struct {
+		name     string
+		flags    []string
+		exitCode int
+		status   string
+		failed   int
+		events   []string // événements attendus dans le journal
+	}{
+		{
+			name: "keep-going", flags: []string{"-keep-going"}, exitCode: exitPartial, status: statusPartial, failed: 1,
+			events: []string{"entry_failed", "convert_done", "convert_partial"},
+		},
+		{name: "sans keep-going", exitCode: exitFailure, status: statusFailure, events: []string{"convert_error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "report.json")
+			args := append([]string{"convert", "-progress", "none", "-log-format", "json", "-report", reportPath}, tt.flags...)
+			args = append(args, input, filepath.Join(t.TempDir(), "output.h5"))
+
+			code, stderr := runCommand(t, args...)
+			if code != tt.exitCode {
+				t.Fatalf("code de sortie %d, attendu %d\n%s", code, tt.exitCode, stderr)
+			}
+
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("lecture du rapport: %v", err)
+			}
+			var run runReport
+			if err := json.Unmarshal(data, &run); err != nil {
+				t.Fatalf("décodage du rapport: %v", err)
+			}
+			if run.Status != tt.status || run.Failed != tt.failed {
+				t.Errorf("rapport: statut %q, %d échecs, attendu %q, %d", run.Status, run.Failed, tt.status, tt.failed)
+			}
+			if tt.failed > 0 && run.Failures[0].Stage != "decode" {
+				t.Errorf("rapport: étape %q, attendu decode", run.Failures[0].Stage)
+			}
+
+			events := make(map[string]bool)
+			for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+				var record struct {
+					Msg   string `json:"msg"`
+					Event string `json:"event"`
+				}
+				if err := json.Unmarshal([]byte(line), &record); err != nil || record.Event == "" {
+					t.Errorf("ligne du journal sans attribut event: %s", line)
+				}
+				events[record.Event] = true
+			}
+			for _, event := range tt.events {
+				if !events[event] {
+					t.Errorf("événement %q absent du journal\n%s", event, stderr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hdf5_test2/converter"
+)
+
+// Modes d'affichage de l'avancement (option -progress)
+const (
+	progressAuto = "auto" // ligne d'avancement si la sortie d'erreur est un terminal, rien sinon
+	progressTTY  = "tty"  // ligne d'avancement réécrite sur place
+	progressJSON = "json" // événements NDJSON, un objet par ligne
+	progressNone = "none"
+)
+
+// Intervalles minimaux entre deux affichages de l'avancement
+const (
+	progressTTYInterval  = 200 * time.Millisecond
+	progressJSONInterval = time.Second
+)
+
+// Surveillance de la conversion : sans nouvel octet lu du JSON source depuis
+// progressStallAfter, l'avancement est signalé comme bloqué (événement "stall", rappelé à
+// chaque intervalle). Une entrée volumineuse en cours de lecture n'est pas un blocage.
+const (
+	progressTickInterval = time.Second
+	progressStallAfter   = 10 * time.Second
+)
+
+// Longueur maximale du nom de série affiché sur la ligne d'avancement
+const progressChannelWidth = 30
+
+// Événement d'avancement du mode json : "start", "progress" (ou "stall" sans nouvel octet
+// lu depuis progressStallAfter) puis "end"
+type progressEvent struct {
+	Event      string   `json:"event"`
+	Time       string   `json:"time"`
+	ElapsedS   float64  `json:"elapsed_s"`
+	BytesRead  int64    `json:"bytes_read"` // octets lus, lecture anticipée comprise
+	BytesDone  int64    `json:"bytes_done"` // octets des entrées déjà traitées
+	BytesTotal int64    `json:"bytes_total"`
+	Entries    int      `json:"entries"`
+	Rows       int64    `json:"rows"`
+	Channel    string   `json:"channel,omitempty"`
+	ETAS       *float64 `json:"eta_s,omitempty"`
+	IdleS      *float64 `json:"idle_s,omitempty"` // secondes sans nouvel octet lu (événement "stall")
+	Status     string   `json:"status,omitempty"` // statut final (événement "end")
+}
+
+// Affichage de l'avancement d'une conversion sur la sortie d'erreur. La conversion
+// appelle update depuis son goroutine, la surveillance (watch) depuis le sien.
+type progressReporter struct {
+	mode      string
+	out       *stderrWriter
+	total     int64        // taille du JSON source
+	read      atomic.Int64 // octets du JSON source lus (voir reader)
+	start     time.Time
+	done      chan struct{}
+	stopped   sync.WaitGroup
+	mu        sync.Mutex // protège les champs suivants
+	last      time.Time  // dernier affichage
+	lastRead  int64      // octets lus au dernier relevé de la surveillance
+	changed   time.Time  // dernier relevé où des octets ont été lus
+	lastStall time.Time  // dernier signalement de blocage
+	current   converter.Progress
+}
+
+// Lecteur du JSON source comptant les octets lus pour la surveillance, qui les relève
+// même pendant le traitement d'une entrée volumineuse
+type progressInput struct {
+	r    io.Reader
+	read *atomic.Int64
+}
+
+func (in progressInput) Read(p []byte) (int, error) {
+	n, err := in.r.Read(p)
+	in.read.Add(int64(n))
+	return n, err
+}
+
+// Fonction pour faire suivre à la surveillance la lecture du JSON source depuis r
+func (p *progressReporter) reader(r io.Reader) io.Reader {
+	return progressInput{r: r, read: &p.read}
+}
+
+// Fonction pour créer l'affichage de l'avancement ; retourne nil si rien ne doit être affiché
+func newProgressReporter(mode string, total int64) (*progressReporter, error) {
+	switch mode {
+	case progressAuto:
+		if !isTerminal(os.Stderr) {
+			return nil, nil
+		}
+		mode = progressTTY
+	case progressTTY, progressJSON:
+	case progressNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("mode d'avancement inconnu: '%s' (attendu auto, tty, json ou none)", mode)
+	}
+
+	now := time.Now()
+	p := &progressReporter{mode: mode, out: stderr, total: total, start: now, changed: now, done: make(chan struct{})}
+	if mode == progressJSON {
+		p.emit("start", "")
+	}
+	p.stopped.Add(1)
+	go p.watch()
+	return p, nil
+}
+
+// Fonction auxiliaire pour savoir si un fichier est un terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Fonction appelée par la conversion après chaque entrée ; l'affichage est limité à un
+// rafraîchissement par intervalle
+func (p *progressReporter) update(progress converter.Progress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = progress
+	interval := progressTTYInterval
+	if p.mode == progressJSON {
+		interval = progressJSONInterval
+	}
+	if time.Since(p.last) < interval {
+		return
+	}
+	p.last = time.Now()
+	if p.mode == progressJSON {
+		p.emit("progress", "")
+		return
+	}
+	p.render()
+}
+
+// Fonction pour surveiller la conversion jusqu'à finish : les octets lus sont relevés, la
+// ligne d'avancement est rafraîchie (temps restant, durée du blocage) et un blocage est
+// signalé en mode json
+func (p *progressReporter) watch() {
+	defer p.stopped.Done()
+	ticker := time.NewTicker(progressTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		p.sampleRead(time.Now())
+		if p.mode == progressJSON {
+			if idle := time.Since(p.changed); idle >= progressStallAfter && time.Since(p.lastStall) >= progressStallAfter {
+				p.lastStall = time.Now()
+				p.emit("stall", "")
+			}
+		} else {
+			p.last = time.Now()
+			p.render()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Fonction auxiliaire pour relever les octets lus : la date du dernier changement sert à
+// détecter un blocage ; p.mu doit être verrouillé
+func (p *progressReporter) sampleRead(now time.Time) {
+	if read := p.read.Load(); read != p.lastRead {
+		p.lastRead = read
+		p.changed = now
+	}
+}
+
+// Fonction pour terminer l'affichage avec le statut final de la conversion
+func (p *progressReporter) finish(status string) {
+	close(p.done)
+	p.stopped.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mode == progressJSON {
+		p.emit("end", status)
+		return
+	}
+	p.render()
+	p.out.endLine()
+}
+
+// Fonction auxiliaire pour estimer le temps restant à partir des octets des entrées déjà
+// traitées (la lecture anticipée n'en fait pas partie) ; false tant que l'estimation
+// n'est pas possible
+func (p *progressReporter) eta() (time.Duration, bool) {
+	done := p.current.BytesDone
+	if done <= 0 || p.total <= 0 || done > p.total {
+		return 0, false
+	}
+	elapsed := time.Since(p.start)
+	return time.Duration(float64(elapsed) * float64(p.total-done) / float64(done)), true
+}
+
+// Fonction pour réécrire la ligne d'avancement (mode tty)
+func (p *progressReporter) render() {
+	line := fmt.Sprintf("%s / %s", formatBytes(uint64(p.current.BytesDone)), formatBytes(uint64(p.total)))
+	if p.total > 0 {
+		line += fmt.Sprintf(" (%.0f%%)", 100*float64(p.current.BytesDone)/float64(p.total))
+	}
+	line += fmt.Sprintf(" · entrées %d · lignes %d", p.current.Entries, p.current.Rows)
+	if channel := p.current.Channel; channel != "" {
+		if runes := []rune(channel); len(runes) > progressChannelWidth {
+			channel = string(runes[:progressChannelWidth-1]) + "…"
+		}
+		line += " · " + channel
+	}
+	if idle := time.Since(p.changed); idle >= progressStallAfter {
+		line += " · bloqué depuis " + idle.Round(time.Second).String()
+	} else if eta, ok := p.eta(); ok {
+		line += " · reste " + eta.Round(time.Second).String()
+	}
+	p.out.setLine(line)
+}
+
+// Fonction pour écrire un événement NDJSON (mode json)
+func (p *progressReporter) emit(event, status string) {
+	now := time.Now()
+	ev := progressEvent{
+		Event:      event,
+		Time:       now.Format(time.RFC3339Nano),
+		ElapsedS:   now.Sub(p.start).Seconds(),
+		BytesRead:  p.read.Load(),
+		BytesDone:  p.current.BytesDone,
+		BytesTotal: p.total,
+		Entries:    p.current.Entries,
+		Rows:       p.current.Rows,
+		Channel:    p.current.Channel,
+		Status:     status,
+	}
+	if eta, ok := p.eta(); ok && event == "progress" {
+		seconds := eta.Seconds()
+		ev.ETAS = &seconds
+	}
+	if event == "stall" {
+		seconds := now.Sub(p.changed).Seconds()
+		ev.IdleS = &seconds
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	p.out.Write(append(data, '\n'))
+}
+
+// Sortie d'erreur commune au journal et à l'avancement. Les écritures sont sérialisées ;
+// la ligne d'avancement du mode tty est effacée avant chaque message du journal, puis
+// réaffichée en dessous.
+type stderrWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	line    string // ligne d'avancement affichée, "" si aucune
+	dropped bool   // ligne d'avancement retirée (dropLine)
+}
+
+var stderr = &stderrWriter{out: os.Stderr}
+
+func (w *stderrWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.line == "" {
+		return w.out.Write(data)
+	}
+	w.clear()
+	n, err := w.out.Write(data)
+	fmt.Fprintf(w.out, "\r%s", w.line)
+	return n, err
+}
+
+// Fonction pour afficher (ou remplacer) la ligne d'avancement
+func (w *stderrWriter) setLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dropped {
+		return
+	}
+	// Effacer la fin de la ligne précédente si elle était plus longue
+	padding := ""
+	if size, previous := len([]rune(line)), len([]rune(w.line)); size < previous {
+		padding = strings.Repeat(" ", previous-size)
+	}
+	fmt.Fprintf(w.out, "\r%s%s", line, padding)
+	w.line = line
+}
+
+// Fonction pour laisser la ligne d'avancement affichée et passer à la ligne suivante
+func (w *stderrWriter) endLine() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.line != "" {
+		fmt.Fprintln(w.out)
+		w.line = ""
+	}
+}
+
+// Fonction pour effacer la ligne d'avancement, qui n'est plus réaffichée ensuite
+// (sortie sur une erreur)
+func (w *stderrWriter) dropLine() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clear()
+	w.line = ""
+	w.dropped = true
+}
+
+// Fonction auxiliaire pour effacer la ligne d'avancement affichée ; w.mu doit être verrouillé
+func (w *stderrWriter) clear() {
+	if w.line != "" {
+		fmt.Fprintf(w.out, "\r%s\r", strings.Repeat(" ", len([]rune(w.line))))
+	}
+}
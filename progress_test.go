@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"hdf5_test2/converter"
+)
+
+// Le temps restant est estimé à partir des octets des entrées traitées, sans la lecture
+// anticipée
+func TestProgressETA(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int64
+		progress converter.Progress
+		want     time.Duration // -1 : pas d'estimation
+	}{
+		{name: "moitié traitée", total: 1000, progress: converter.Progress{BytesRead: 900, BytesDone: 500}, want: 10 * time.Second},
+		{name: "quart traité", total: 1000, progress: converter.Progress{BytesRead: 1000, BytesDone: 250}, want: 30 * time.Second},
+		{name: "rien de traité", total: 1000, progress: converter.Progress{BytesRead: 600}, want: -1},
+		{name: "taille inconnue", progress: converter.Progress{BytesRead: 600, BytesDone: 500}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &progressReporter{total: tt.total, start: time.Now().Add(-10 * time.Second), current: tt.progress}
+			eta, ok := p.eta()
+			if !ok {
+				if tt.want >= 0 {
+					t.Errorf("pas d'estimation, attendu %v", tt.want)
+				}
+				return
+			}
+			if tt.want < 0 || eta < tt.want-time.Second || eta > tt.want+time.Second {
+				t.Errorf("temps restant %v, attendu %v", eta, tt.want)
+			}
+		})
+	}
+}
+
+// Un blocage est mesuré depuis le dernier octet lu, même sans entrée terminée
+func TestProgressSampleRead(t *testing.T) {
+	start := time.Now()
+	p := &progressReporter{start: start, changed: start}
+	steps := []struct {
+		read    int64 // octets lus au relevé
+		changed bool  // date du dernier changement mise à jour
+	}{
+		{read: 0},
+		{read: 4096, changed: true},
+		{read: 4096},
+		{read: 8192, changed: true},
+	}
+	for i, step := range steps {
+		now := start.Add(time.Duration(i+1) * time.Second)
+		p.read.Store(step.read)
+		p.sampleRead(now)
+		if got := p.changed.Equal(now); got != step.changed {
+			t.Errorf("relevé %d (%d octets): changement %v, attendu %v", i, step.read, got, step.changed)
+		}
+	}
+}
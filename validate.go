@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"hdf5_test2/converter"
+)
+
+// Commande validate : vérifie la configuration et le fichier JSON avec les mêmes règles
+// que la conversion (décodage, champs inconnus, conversion des valeurs), sans rien écrire
+func runValidate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	logOpts := addLogFlags(flags)
+	cfgFlags := addConfigFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("Usage: ./hdf5_test2 validate [options] input.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := logOpts.setup(); err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	inputFile := flags.Arg(0)
+
+	cfg, err := cfgFlags.config()
+	if err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	input, err := os.Open(inputFile)
+	if err != nil {
+		fatal("input_open_error", "Erreur lors de la lecture du fichier JSON", "error", err)
+	}
+	defer input.Close()
+
+	// Le JSON est lu par le pipeline de la conversion : mêmes règles de décodage, de mode
+	// strict et de conversion des valeurs
+	report, err := converter.ValidateInput(context.Background(), input, cfg.options())
+	if err != nil {
+		fatal("validate_error", "JSON invalide", errorAttrs(err)...)
+	}
+	for _, failure := range report.Failures {
+		slog.Warn("Entrée rejetée", append([]any{"event", "entry_rejected"}, failureAttrs(failure)...)...)
+	}
+
+	// Les champs inconnus ne sont une erreur qu'en mode strict
+	if report.UnknownFields > 0 {
+		slog.Warn("Champs inconnus conservés en attributs x_*", "event", "unknown_fields", "entries", report.UnknownFields, "first", report.FirstUnknown)
+	}
+
+	printWarningSummary(report.ChannelWarnings)
+
+	profile := cfg.Profile
+	if profile == "" {
+		profile = "aucun"
+	}
+	slog.Info("Configuration valide", "event", "config_valid", "profile", profile, "format", cfg.Format, "metadata", cfg.Metadata,
+		"compression", cfg.Compression, "chunking", cfg.Chunking)
+	slog.Info("JSON valide", "event", "input_valid", "input", inputFile, "entries", report.Entries, "empty", report.Empty,
+		"renamed", report.Renamed, "warnings", report.Warnings)
+	if len(report.Failures) > 0 {
+		slog.Warn("Entrées rejetées, ignorées par la conversion en mode keep-going", "event", "validate_partial", "failed", len(report.Failures))
+		input.Close()
+		os.Exit(exitPartial)
+	}
+}
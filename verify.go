@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"gonum.org/v1/hdf5"
+
+	"hdf5_test2/converter"
+)
+
+// Commande verify : relit le JSON source avec les mêmes règles que la conversion et
+// compare chaque série attendue au contenu du fichier HDF5 (dimensions, valeurs,
+// attributs). Sert de contrôle avant la suppression des fichiers JSON sources.
+func runVerify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	logOpts := addLogFlags(flags)
+	cfgFlags := addConfigFlags(flags)
+	tolerance := flags.Float64("tolerance", 1e-9, "écart relatif toléré entre valeurs flottantes")
+	flags.Usage = func() {
+		fmt.Println("Usage: ./hdf5_test2 verify [options] input.json output.h5")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := logOpts.setup(); err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	inputFile := flags.Arg(0)
+	outputFile := flags.Arg(1)
+
+	// La vérification reprend la configuration de la conversion
+	cfg, err := cfgFlags.config()
+	if err != nil {
+		fatal("config_error", "Erreur de configuration", "error", err)
+	}
+
+	input, err := os.Open(inputFile)
+	if err != nil {
+		fatal("input_open_error", "Erreur lors de la lecture du fichier JSON", "error", err)
+	}
+	defer input.Close()
+
+	f, err := hdf5.OpenFile(outputFile, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		fatal("hdf5_open_error", "Erreur lors de l'ouverture du fichier HDF5", "error", err)
+	}
+	defer f.Close()
+
+	// Le JSON est relu par le pipeline de la conversion, avec les mêmes règles
+	report, err := converter.VerifyFile(context.Background(), input, f, cfg.options(), *tolerance)
+	if errors.Is(err, converter.ErrUnsupportedFormat) {
+		fatal("unsupported_format", "Format non pris en charge par la vérification", "error", err)
+	}
+	if err != nil {
+		fatal("verify_error", "Erreur lors de la vérification", errorAttrs(err)...)
+	}
+
+	for _, name := range report.Missing {
+		slog.Warn("Série manquante", "event", "entry_missing", "path", name)
+	}
+	for _, name := range report.Extra {
+		slog.Warn("Série en trop", "event", "entry_extra", "path", name)
+	}
+	for _, mismatch := range report.Mismatched {
+		slog.Warn("Série différente", "event", "entry_mismatch", "path", mismatch.Name, "reason", mismatch.Reason)
+	}
+	slog.Info("Séries vérifiées", "event", "verify_summary", "checked", report.Checked,
+		"matching", report.Checked-len(report.Missing)-report.MismatchedCount(), "missing", len(report.Missing),
+		"extra", len(report.Extra), "mismatched", report.MismatchedCount())
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+	slog.Info("Vérification réussie", "event", "verify_done", "output", outputFile, "input", inputFile)
+}